@@ -0,0 +1,184 @@
+// Package accesslog provides net/http middleware that writes access logs
+// with the labels recommended at http://ltsv.org/.
+//
+// Use it with a logger built from ltsv.NewAccessLogConfig, or with an
+// encoder created from ltsv.NewAccessLogEncoderConfig, to get lines which
+// existing LTSV access log parsers for Apache and nginx understand:
+//
+//	host:127.0.0.1	ident:-	user:frank	time:[10/Oct/2000:13:55:36 -0700]	req:GET /apache_pb.gif HTTP/1.0	method:GET	uri:/apache_pb.gif	protocol:HTTP/1.0	status:200	size:2326	reqsize:412	referer:http://www.example.com/start.html	ua:Mozilla/4.08	vhost:www.example.com	reqtime:0.000123	reqtime_microsec:123	forwardedfor:-	apptime:0.000101
+package accesslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Middleware returns a function which wraps a handler with NewHandler.
+func Middleware(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return NewHandler(logger, next)
+	}
+}
+
+// NewHandler returns a handler which calls next and then logs the request
+// at InfoLevel with the following labels:
+//
+//	host              remote address without the port
+//	ident             always "-"
+//	user              basic authentication user name
+//	time              time when the request started
+//	req               request line, e.g. "GET / HTTP/1.1"
+//	method            request method
+//	uri               request URI
+//	protocol          request protocol
+//	status            response status code
+//	size              response body size in bytes
+//	reqsize           request size in bytes, including headers
+//	referer           Referer header
+//	ua                User-Agent header
+//	vhost             Host header
+//	reqtime           time taken to serve the request, in seconds
+//	reqtime_microsec  time taken to serve the request, in microseconds
+//	forwardedfor      X-Forwarded-For header
+//	apptime           time until the handler wrote the response header
+//
+// Empty values are logged as "-".
+func NewHandler(logger *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		rw := &responseWriter{ResponseWriter: w, start: start}
+
+		next.ServeHTTP(rw, r)
+
+		reqtime := time.Since(start)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+			rw.apptime = reqtime
+		}
+		user, _, _ := r.BasicAuth()
+		reqLine := r.Method + " " + r.RequestURI + " " + r.Proto
+		logger.Info("",
+			zap.String("host", orDash(remoteHost(r.RemoteAddr))),
+			zap.String("ident", "-"),
+			zap.String("user", orDash(user)),
+			zap.Time("time", start),
+			zap.String("req", reqLine),
+			zap.String("method", r.Method),
+			zap.String("uri", r.RequestURI),
+			zap.String("protocol", r.Proto),
+			zap.Int("status", rw.status),
+			zap.Int64("size", rw.size),
+			zap.Int64("reqsize", headerSize(reqLine, r.Header)+body.n),
+			zap.String("referer", orDash(r.Referer())),
+			zap.String("ua", orDash(r.UserAgent())),
+			zap.String("vhost", orDash(r.Host)),
+			zap.Duration("reqtime", reqtime),
+			zap.Int64("reqtime_microsec", int64(reqtime/time.Microsecond)),
+			zap.String("forwardedfor", orDash(r.Header.Get("X-Forwarded-For"))),
+			zap.Duration("apptime", rw.apptime),
+		)
+	})
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// headerSize returns the size of the request line and headers as sent on
+// the wire by HTTP/1.x.
+func headerSize(reqLine string, h http.Header) int64 {
+	n := int64(len(reqLine) + 2)
+	for k, vs := range h {
+		for _, v := range vs {
+			n += int64(len(k) + 2 + len(v) + 2)
+		}
+	}
+	return n + 2
+}
+
+// countingReader counts the bytes of the request body read by the handler.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// responseWriter records the status and size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	start   time.Time
+	status  int
+	size    int64
+	apptime time.Duration
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.apptime = time.Since(w.start)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying writer does.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer does.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("accesslog: response writer does not implement http.Hijacker")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+		w.apptime = time.Since(w.start)
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
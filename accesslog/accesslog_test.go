@@ -0,0 +1,99 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"github.com/hnakamur/zap-ltsv/accesslog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(
+		ltsv.NewLTSVEncoder(ltsv.NewAccessLogEncoderConfig()),
+		zapcore.AddSync(&buf),
+		zapcore.InfoLevel,
+	)
+	logger := zap.New(core)
+
+	h := accesslog.Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello")
+	}))
+
+	req := httptest.NewRequest("POST", "http://www.example.com/path?q=1", strings.NewReader("body"))
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.SetBasicAuth("frank", "secret")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec, err := ltsv.ParseRecord(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), ltsv.JSONEscape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLabels := []string{
+		"host", "ident", "user", "time", "req", "method", "uri", "protocol",
+		"status", "size", "reqsize", "referer", "ua", "vhost", "reqtime",
+		"reqtime_microsec", "forwardedfor", "apptime",
+	}
+	if len(rec) != len(wantLabels) {
+		t.Fatalf("got %d labels, want %d: %q", len(rec), len(wantLabels), buf.String())
+	}
+	for i, label := range wantLabels {
+		if rec[i].Label != label {
+			t.Errorf("label %d: got=%q, want=%q", i, rec[i].Label, label)
+		}
+	}
+	want := map[string]string{
+		"host":         "192.0.2.1",
+		"ident":        "-",
+		"user":         "frank",
+		"req":          "POST http://www.example.com/path?q=1 HTTP/1.1",
+		"method":       "POST",
+		"protocol":     "HTTP/1.1",
+		"status":       "201",
+		"size":         "5",
+		"referer":      "-",
+		"ua":           "test-agent",
+		"vhost":        "www.example.com",
+		"forwardedfor": "198.51.100.1",
+	}
+	for label, v := range want {
+		if got, _ := rec.Get(label); got != v {
+			t.Errorf("%s: got=%q, want=%q", label, got, v)
+		}
+	}
+	if tm, _ := rec.Get("time"); !strings.HasPrefix(tm, "[") || !strings.HasSuffix(tm, "]") {
+		t.Errorf("time: got=%q, want CLF format", tm)
+	}
+}
+
+func TestHandlerDefaultStatus(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(
+		ltsv.NewLTSVEncoder(ltsv.NewAccessLogEncoderConfig()),
+		zapcore.AddSync(&buf),
+		zapcore.InfoLevel,
+	)
+	h := accesslog.NewHandler(zap.New(core), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	rec, err := ltsv.ParseRecord(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), ltsv.JSONEscape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for label, v := range map[string]string{"status": "200", "size": "0", "user": "-", "ua": "-"} {
+		if got, _ := rec.Get(label); got != v {
+			t.Errorf("%s: got=%q, want=%q", label, got, v)
+		}
+	}
+}
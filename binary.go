@@ -0,0 +1,158 @@
+package ltsv
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// A BinaryEncoding selects how the encoder writes binary values added
+// with AddBinary, such as zap.Binary fields.
+type BinaryEncoding int
+
+const (
+	// StdBase64Binary writes standard base64 with padding. This is the
+	// default encoding.
+	StdBase64Binary BinaryEncoding = iota
+	// URLBase64Binary writes base64url with padding.
+	URLBase64Binary
+	// RawStdBase64Binary writes standard base64 without padding.
+	RawStdBase64Binary
+	// HexBinary writes lower-case hexadecimal.
+	HexBinary
+	// EscapedBinary writes printable ASCII characters except the backslash
+	// as is and other bytes as \xNN, as in Go string literals. The result
+	// is then escaped like any other string.
+	EscapedBinary
+)
+
+// String returns a lower-case ASCII representation of the encoding.
+func (e BinaryEncoding) String() string {
+	switch e {
+	case StdBase64Binary:
+		return "base64"
+	case URLBase64Binary:
+		return "base64url"
+	case RawStdBase64Binary:
+		return "rawbase64"
+	case HexBinary:
+		return "hex"
+	case EscapedBinary:
+		return "escaped"
+	default:
+		return fmt.Sprintf("BinaryEncoding(%d)", int(e))
+	}
+}
+
+// MarshalText marshals the encoding to text. See String.
+func (e BinaryEncoding) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText unmarshals text to an encoding. Valid values are
+// "base64", "base64url", "rawbase64", "hex" and "escaped".
+func (e *BinaryEncoding) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "base64", "":
+		*e = StdBase64Binary
+	case "base64url":
+		*e = URLBase64Binary
+	case "rawbase64":
+		*e = RawStdBase64Binary
+	case "hex":
+		*e = HexBinary
+	case "escaped":
+		*e = EscapedBinary
+	default:
+		return fmt.Errorf("ltsv: unknown binary encoding %q", text)
+	}
+	return nil
+}
+
+// BinaryEncoder sets the encoding of binary values.
+func BinaryEncoder(e BinaryEncoding) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.binaryEncoding = e
+	})
+}
+
+// BinaryEncoderByKey overrides the encoding of BinaryEncoder for binary
+// values with the given keys. Keys are matched against the key of the
+// field itself, without the prefix of flattened labels.
+func BinaryEncoderByKey(encodings map[string]BinaryEncoding) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.binaryEncodingByKey = make(map[string]BinaryEncoding, len(encodings))
+		for k, v := range encodings {
+			opts.binaryEncodingByKey[k] = v
+		}
+	})
+}
+
+// binaryEncodingOf returns the encoding for a binary value with key.
+func (o *encoderOptions) binaryEncodingOf(key string) BinaryEncoding {
+	if e, ok := o.binaryEncodingByKey[key]; ok {
+		return e
+	}
+	return o.binaryEncoding
+}
+
+// binaryChunk is the number of input bytes encoded at a time. It is a
+// multiple of 3, so that base64 is only padded at the end.
+const binaryChunk = 48
+
+// appendBinary appends val in the encoding e, quoted inside JSON values.
+// It encodes through a small array instead of allocating a string.
+func (enc *ltsvEncoder) appendBinary(val []byte, e BinaryEncoding) {
+	enc.addElementSeparator()
+	quoted := enc.nestedLevel > 0 || enc.openNamespaces > 0
+	if quoted {
+		enc.buf.AppendByte('"')
+	}
+	var scratch [4 * binaryChunk]byte
+	switch e {
+	case HexBinary:
+		for _, b := range val {
+			enc.buf.AppendByte(hex[b>>4])
+			enc.buf.AppendByte(hex[b&0xF])
+		}
+	case EscapedBinary:
+		for len(val) > 0 {
+			n := 0
+			for len(val) > 0 && n+4 <= len(scratch) {
+				b := val[0]
+				val = val[1:]
+				if 0x20 <= b && b < 0x7f && b != '\\' {
+					scratch[n] = b
+					n++
+					continue
+				}
+				scratch[n] = '\\'
+				scratch[n+1] = 'x'
+				scratch[n+2] = hex[b>>4]
+				scratch[n+3] = hex[b&0xF]
+				n += 4
+			}
+			enc.safeAddByteString(scratch[:n])
+		}
+	default:
+		b64 := base64.StdEncoding
+		switch e {
+		case URLBase64Binary:
+			b64 = base64.URLEncoding
+		case RawStdBase64Binary:
+			b64 = base64.RawStdEncoding
+		}
+		for len(val) > 0 {
+			n := len(val)
+			if n > binaryChunk {
+				n = binaryChunk
+			}
+			m := b64.EncodedLen(n)
+			b64.Encode(scratch[:m], val[:n])
+			enc.buf.Write(scratch[:m])
+			val = val[n:]
+		}
+	}
+	if quoted {
+		enc.buf.AppendByte('"')
+	}
+}
@@ -0,0 +1,94 @@
+package ltsv_test
+
+import (
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBinaryEncoder(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	data := []byte{0xfb, 0xff, 'a', '\\'}
+	long := make([]byte, 100)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	testCases := []struct {
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			fields: []zapcore.Field{zap.Binary("b", data)},
+			want:   "level:info\tmsg:hello\tb:+/9hXA==\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.BinaryEncoder(ltsv.URLBase64Binary)},
+			fields: []zapcore.Field{zap.Binary("b", data)},
+			want:   "level:info\tmsg:hello\tb:-_9hXA==\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.BinaryEncoder(ltsv.RawStdBase64Binary)},
+			fields: []zapcore.Field{zap.Binary("b", data)},
+			want:   "level:info\tmsg:hello\tb:+/9hXA\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.BinaryEncoder(ltsv.HexBinary)},
+			fields: []zapcore.Field{zap.Dict("req", zap.Binary("b", data))},
+			want:   "level:info\tmsg:hello\treq:{\"b\":\"fbff615c\"}\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.BinaryEncoder(ltsv.EscapedBinary), ltsv.ValueEscaping(ltsv.MinimalEscape)},
+			fields: []zapcore.Field{zap.Binary("b", data), zap.Dict("req", zap.Binary("b", data))},
+			want:   "level:info\tmsg:hello\tb:\\xfb\\xffa\\x5c\treq:{\"b\":\"\\\\xfb\\\\xffa\\\\x5c\"}\n",
+		},
+		{
+			opts: []ltsv.Option{
+				ltsv.BinaryEncoderByKey(map[string]ltsv.BinaryEncoding{"id": ltsv.HexBinary}),
+				ltsv.Flatten(".", 0),
+			},
+			fields: []zapcore.Field{zap.Dict("req", zap.Binary("id", data), zap.Binary("body", data))},
+			want:   "level:info\tmsg:hello\treq.id:fbff615c\treq.body:+/9hXA==\n",
+		},
+		{
+			fields: []zapcore.Field{zap.Binary("b", long)},
+			want:   "level:info\tmsg:hello\tb:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4vMDEyMzQ1Njc4OTo7PD0+P0BBQkNERUZHSElKS0xNTk9QUVJTVFVWV1hZWltcXV5fYGFiYw==\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestBinaryEncodingText(t *testing.T) {
+	for _, e := range []ltsv.BinaryEncoding{
+		ltsv.StdBase64Binary, ltsv.URLBase64Binary, ltsv.RawStdBase64Binary, ltsv.HexBinary, ltsv.EscapedBinary,
+	} {
+		text, err := e.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got ltsv.BinaryEncoding
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("failed to unmarshal %q; err=%v", text, err)
+		}
+		if got != e {
+			t.Errorf("got=%v, want=%v", got, e)
+		}
+	}
+	var e ltsv.BinaryEncoding
+	if err := e.UnmarshalText([]byte("base32")); err == nil {
+		t.Errorf("got no error for unknown encoding, want error")
+	}
+}
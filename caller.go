@@ -0,0 +1,133 @@
+package ltsv
+
+import (
+	"path"
+	"runtime/debug"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Default labels of SplitCaller.
+const (
+	DefaultCallerFileKey = "caller_file"
+	DefaultCallerLineKey = "caller_line"
+	DefaultCallerFuncKey = "caller_func"
+)
+
+// SplitCaller makes the encoder write the caller as separate labels for
+// the file, the line and the function instead of a single label written
+// with EncodeCaller. An empty key selects the default label. The labels
+// are written only if the CallerKey of the EncoderConfig is not empty.
+//
+// The file is written as recorded by zap, that is as an absolute path
+// unless the binary was built with -trimpath, after trimming with
+// TrimCallerPrefixes or TrimCallerToModule.
+func SplitCaller(fileKey, lineKey, funcKey string) Option {
+	if fileKey == "" {
+		fileKey = DefaultCallerFileKey
+	}
+	if lineKey == "" {
+		lineKey = DefaultCallerLineKey
+	}
+	if funcKey == "" {
+		funcKey = DefaultCallerFuncKey
+	}
+	return optionFunc(func(opts *encoderOptions) {
+		opts.splitCaller = true
+		opts.callerFileKey = fileKey
+		opts.callerLineKey = lineKey
+		opts.callerFuncKey = funcKey
+	})
+}
+
+// TrimCallerPrefixes removes the first of the prefixes the file of the
+// caller starts with, e.g. "/home/build/src/".
+func TrimCallerPrefixes(prefixes ...string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.callerPrefixes = append([]string(nil), prefixes...)
+	})
+}
+
+// TrimCallerToModule makes the encoder write the file of the caller as
+// the import path of its package followed by the file name, relative to
+// the Go module modulePath, e.g. "internal/server/handler.go" for a file
+// in the package "example.com/app/internal/server" of the module
+// "example.com/app". Files in other modules keep the full import path of
+// their package. If modulePath is empty, the main module of the binary is
+// used.
+//
+// The package is derived from the function of the caller, so the file is
+// kept as is if zap did not record the function.
+func TrimCallerToModule(modulePath string) Option {
+	if modulePath == "" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			modulePath = info.Main.Path
+		}
+	}
+	return optionFunc(func(opts *encoderOptions) {
+		opts.trimToModule = true
+		opts.modulePath = modulePath
+	})
+}
+
+// trimCaller returns the caller with its file trimmed as configured.
+func (o *encoderOptions) trimCaller(caller zapcore.EntryCaller) zapcore.EntryCaller {
+	if o.trimToModule {
+		if pkg := funcPackage(caller.Function); pkg != "" {
+			file := pkg + "/" + path.Base(caller.File)
+			if o.modulePath != "" && strings.HasPrefix(file, o.modulePath+"/") {
+				file = file[len(o.modulePath)+1:]
+			}
+			caller.File = file
+			return caller
+		}
+	}
+	for _, prefix := range o.callerPrefixes {
+		if strings.HasPrefix(caller.File, prefix) {
+			caller.File = caller.File[len(prefix):]
+			break
+		}
+	}
+	return caller
+}
+
+// funcPackage returns the import path of the package of a function name
+// like "example.com/app/server.(*Server).handle".
+func funcPackage(fn string) string {
+	slash := strings.LastIndexByte(fn, '/')
+	dot := strings.IndexByte(fn[slash+1:], '.')
+	if dot < 0 {
+		return ""
+	}
+	return fn[:slash+1+dot]
+}
+
+// addCaller writes the labels of the caller and its function.
+func (enc *ltsvEncoder) addCaller(caller zapcore.EntryCaller) {
+	caller = enc.opts.trimCaller(caller)
+	funcKey := enc.FunctionKey
+	if enc.CallerKey != "" {
+		if enc.opts.splitCaller {
+			enc.AddString(enc.opts.callerFileKey, caller.File)
+			enc.AddInt(enc.opts.callerLineKey, caller.Line)
+			if caller.Function != "" {
+				enc.AddString(enc.opts.callerFuncKey, caller.Function)
+			}
+			if funcKey == enc.opts.callerFuncKey {
+				funcKey = ""
+			}
+		} else if enc.addKey(enc.CallerKey) {
+			cur := enc.buf.Len()
+			enc.EncodeCaller(caller, enc)
+			if cur == enc.buf.Len() {
+				// User-supplied EncodeCaller was a no-op. Fall back to
+				// strings to keep output JSON valid.
+				enc.AppendString(caller.String())
+			}
+		}
+	}
+	if funcKey != "" {
+		enc.AddString(funcKey, caller.Function)
+	}
+}
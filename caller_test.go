@@ -0,0 +1,70 @@
+package ltsv_test
+
+import (
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCaller(t *testing.T) {
+	caller := zapcore.EntryCaller{
+		Defined:  true,
+		File:     "/home/build/src/app/internal/server/handler.go",
+		Line:     42,
+		Function: "example.com/app/internal/server.(*Server).handle",
+	}
+	testCases := []struct {
+		cfg  func(*zapcore.EncoderConfig)
+		opts []ltsv.Option
+		want string
+	}{
+		{
+			want: "caller:server/handler.go:42\tmsg:hello\n",
+		},
+		{
+			cfg:  func(cfg *zapcore.EncoderConfig) { cfg.FunctionKey = "func" },
+			want: "caller:server/handler.go:42\tfunc:example.com/app/internal/server.(*Server).handle\tmsg:hello\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.SplitCaller("", "", "")},
+			want: "caller_file:/home/build/src/app/internal/server/handler.go\tcaller_line:42\tcaller_func:example.com/app/internal/server.(*Server).handle\tmsg:hello\n",
+		},
+		{
+			cfg:  func(cfg *zapcore.EncoderConfig) { cfg.FunctionKey = "fn" },
+			opts: []ltsv.Option{ltsv.SplitCaller("file", "line", "fn"), ltsv.TrimCallerPrefixes("/tmp/", "/home/build/src/")},
+			want: "file:app/internal/server/handler.go\tline:42\tfn:example.com/app/internal/server.(*Server).handle\tmsg:hello\n",
+		},
+		{
+			cfg:  func(cfg *zapcore.EncoderConfig) { cfg.EncodeCaller = zapcore.FullCallerEncoder },
+			opts: []ltsv.Option{ltsv.TrimCallerToModule("example.com/app")},
+			want: "caller:internal/server/handler.go:42\tmsg:hello\n",
+		},
+		{
+			cfg:  func(cfg *zapcore.EncoderConfig) { cfg.EncodeCaller = zapcore.FullCallerEncoder },
+			opts: []ltsv.Option{ltsv.TrimCallerToModule("example.com/other")},
+			want: "caller:example.com/app/internal/server/handler.go:42\tmsg:hello\n",
+		},
+		{
+			cfg:  func(cfg *zapcore.EncoderConfig) { cfg.CallerKey = "" },
+			opts: []ltsv.Option{ltsv.SplitCaller("", "", "")},
+			want: "msg:hello\n",
+		},
+	}
+	for _, tc := range testCases {
+		cfg := ltsv.NewDevelopmentEncoderConfig()
+		cfg.TimeKey = ""
+		cfg.LevelKey = ""
+		if tc.cfg != nil {
+			tc.cfg(&cfg)
+		}
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello", Caller: caller}, nil)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
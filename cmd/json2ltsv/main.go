@@ -0,0 +1,93 @@
+// Command json2ltsv converts JSON objects, one per line, to LTSV lines
+// written by the zap LTSV encoder.
+//
+// Usage:
+//
+//	json2ltsv [flags] [file ...]
+//
+// See ltsvjson.ToLTSV for how values are converted.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"github.com/hnakamur/zap-ltsv/ltsvjson"
+)
+
+func main() {
+	var (
+		flatten  = flag.String("flatten", "", "write nested values as separate labels joined with this separator, e.g. \".\"")
+		maxDepth = flag.Int("max-depth", 0, "maximum depth to flatten; 0 means no limit")
+		escape   = flag.String("escape", "json", "escape mode of the output: json, minimal or percent")
+		strict   = flag.Bool("strict", false, "replace characters outside of the ltsv.org label charset in keys")
+	)
+	flag.Parse()
+
+	var mode ltsv.EscapeMode
+	if err := mode.UnmarshalText([]byte(*escape)); err != nil {
+		fatal(err)
+	}
+	opts := []ltsv.Option{
+		ltsv.ValueEscaping(mode),
+		ltsv.InvalidKeyPolicy(ltsv.ReplaceInvalidKey),
+	}
+	if *flatten != "" {
+		opts = append(opts, ltsv.Flatten(*flatten, *maxDepth))
+	}
+	if *strict {
+		opts = append(opts, ltsv.StrictKeys())
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if flag.NArg() == 0 {
+		if err := convert(w, os.Stdin, opts); err != nil {
+			fatal(err)
+		}
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		err = convert(w, f, opts)
+		f.Close()
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "json2ltsv: %v\n", err)
+	os.Exit(1)
+}
+
+func convert(w *bufio.Writer, r io.Reader, opts []ltsv.Option) error {
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			b, cerr := ltsvjson.ToLTSV(line, opts...)
+			if cerr != nil {
+				return fmt.Errorf("line %d: %v", lineNo, cerr)
+			}
+			w.Write(b)
+			w.WriteByte('\n')
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
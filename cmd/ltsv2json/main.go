@@ -0,0 +1,71 @@
+// Command ltsv2json converts LTSV lines to JSON objects, one per line.
+//
+// Usage:
+//
+//	ltsv2json [flags] [file ...]
+//
+// See ltsvjson.ToJSON for how values are converted.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"github.com/hnakamur/zap-ltsv/ltsvjson"
+)
+
+func main() {
+	var (
+		unflatten = flag.String("unflatten", "", "separator of flattened labels to nest into objects, e.g. \".\"")
+		escape    = flag.String("escape", "json", "escape mode of the input: json, minimal or percent")
+	)
+	flag.Parse()
+
+	var mode ltsv.EscapeMode
+	if err := mode.UnmarshalText([]byte(*escape)); err != nil {
+		fatal(err)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	if flag.NArg() == 0 {
+		if err := convert(w, os.Stdin, mode, *unflatten); err != nil {
+			fatal(err)
+		}
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		err = convert(w, f, mode, *unflatten)
+		f.Close()
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", name, err))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ltsv2json: %v\n", err)
+	os.Exit(1)
+}
+
+func convert(w *bufio.Writer, r io.Reader, mode ltsv.EscapeMode, separator string) error {
+	dec := ltsv.NewDecoder(r)
+	dec.EscapeMode = mode
+	for dec.Scan() {
+		b, err := ltsvjson.ToJSON(dec.Record(), separator)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", dec.Line(), err)
+		}
+		w.Write(b)
+		w.WriteByte('\n')
+	}
+	return dec.Err()
+}
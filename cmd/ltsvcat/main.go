@@ -0,0 +1,132 @@
+// Command ltsvcat pretty-prints LTSV logs written by the zap LTSV encoder.
+//
+// Usage:
+//
+//	ltsvcat [flags] [file ...]
+//
+// It reads the files, or the standard input if none are given, and prints
+// each line with the time, level, logger, caller and message labels of
+// ltsv.NewDevelopmentEncoderConfig as aligned columns, followed by the
+// remaining labels as key=value. Nested JSON values and stacktraces are
+// printed on the following lines. Lines which are not valid LTSV are
+// printed as they are.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+)
+
+func main() {
+	var (
+		follow   = flag.Bool("f", false, "keep reading the last file as it grows, like tail -f")
+		color    = flag.String("color", "auto", "colorize levels: auto, always or never")
+		show     = flag.String("show", "", "comma-separated labels to show; all labels if empty")
+		hide     = flag.String("hide", "", "comma-separated labels to hide")
+		escape   = flag.String("escape", "json", "escape mode of the input: json, minimal or percent")
+		interval = flag.Duration("interval", 250*time.Millisecond, "polling interval in follow mode")
+	)
+	flag.Parse()
+
+	p := newPrinter(os.Stdout, ltsv.NewDevelopmentEncoderConfig())
+	if err := p.escapeMode.UnmarshalText([]byte(*escape)); err != nil {
+		fatal(err)
+	}
+	switch *color {
+	case "always":
+		p.color = true
+	case "never":
+		p.color = false
+	case "auto":
+		p.color = isTerminal(os.Stdout)
+	default:
+		fatal(fmt.Errorf("invalid -color value %q", *color))
+	}
+	p.show = splitList(*show)
+	p.hide = splitList(*hide)
+
+	if flag.NArg() == 0 {
+		if err := p.printAll(os.Stdin); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	for i, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		var r io.Reader = f
+		if *follow && i == flag.NArg()-1 {
+			r = &followReader{r: f, interval: *interval}
+		}
+		err = p.printAll(r)
+		f.Close()
+		if err != nil {
+			fatal(err)
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ltsvcat: %v\n", err)
+	os.Exit(1)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// followReader keeps waiting for more data at the end of the file.
+type followReader struct {
+	r        io.Reader
+	interval time.Duration
+}
+
+func (f *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		time.Sleep(f.interval)
+	}
+}
+
+// printAll prints every line read from r.
+func (p *printer) printAll(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			p.printLine(line)
+		}
+		if err == io.EOF {
+			return p.w.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if br.Buffered() == 0 {
+			// Show output promptly when reading interactively or following.
+			if err := p.w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	colorRed     = 31
+	colorYellow  = 33
+	colorBlue    = 34
+	colorMagenta = 35
+)
+
+// levelColors follows zapcore.LowercaseColorLevelEncoder.
+var levelColors = map[string]int{
+	"debug":  colorMagenta,
+	"info":   colorBlue,
+	"warn":   colorYellow,
+	"error":  colorRed,
+	"dpanic": colorRed,
+	"panic":  colorRed,
+	"fatal":  colorRed,
+}
+
+// printer renders LTSV lines for humans.
+type printer struct {
+	w          *bufio.Writer
+	cfg        zapcore.EncoderConfig
+	escapeMode ltsv.EscapeMode
+	color      bool
+	show       []string
+	hide       []string
+
+	// widths of the time, level, logger and caller columns seen so far,
+	// so that columns stay aligned as lines are printed.
+	widths [4]int
+}
+
+func newPrinter(w io.Writer, cfg zapcore.EncoderConfig) *printer {
+	return &printer{w: bufio.NewWriter(w), cfg: cfg}
+}
+
+func (p *printer) visible(label string) bool {
+	for _, h := range p.hide {
+		if h == label {
+			return false
+		}
+	}
+	if len(p.show) == 0 {
+		return true
+	}
+	for _, s := range p.show {
+		if s == label {
+			return true
+		}
+	}
+	return false
+}
+
+// printLine prints a line including the trailing newline, if any.
+func (p *printer) printLine(line []byte) {
+	line = bytes.TrimRight(line, "\r\n")
+	rec, err := ltsv.ParseRecord(line, p.escapeMode)
+	if err != nil {
+		p.w.Write(line)
+		p.w.WriteByte('\n')
+		return
+	}
+
+	columnKeys := [4]string{p.cfg.TimeKey, p.cfg.LevelKey, p.cfg.NameKey, p.cfg.CallerKey}
+	var columns [4]string
+	var msg, stack string
+	var rest []ltsv.Pair
+	for _, pair := range rec {
+		if !p.visible(pair.Label) {
+			continue
+		}
+		switch pair.Label {
+		case columnKeys[0]:
+			columns[0] = pair.Value
+		case columnKeys[1]:
+			columns[1] = pair.Value
+		case columnKeys[2]:
+			columns[2] = pair.Value
+		case columnKeys[3]:
+			columns[3] = pair.Value
+		case p.cfg.MessageKey:
+			msg = pair.Value
+		case p.cfg.StacktraceKey:
+			stack = pair.Value
+		default:
+			rest = append(rest, pair)
+		}
+	}
+
+	sep := false
+	for i, col := range columns {
+		if col == "" && p.widths[i] == 0 {
+			continue
+		}
+		if sep {
+			p.w.WriteString("  ")
+		}
+		sep = true
+		if len(col) > p.widths[i] {
+			p.widths[i] = len(col)
+		}
+		padding := strings.Repeat(" ", p.widths[i]-len(col))
+		if i == 1 {
+			p.writeLevel(col)
+		} else {
+			p.w.WriteString(col)
+		}
+		p.w.WriteString(padding)
+	}
+	if msg != "" {
+		if sep {
+			p.w.WriteString("  ")
+		}
+		sep = true
+		p.w.WriteString(msg)
+	}
+
+	var nested []ltsv.Pair
+	for _, pair := range rest {
+		if isJSON(pair.Value) {
+			nested = append(nested, pair)
+			continue
+		}
+		if sep {
+			p.w.WriteByte(' ')
+		}
+		sep = true
+		p.w.WriteString(pair.Label)
+		p.w.WriteByte('=')
+		p.w.WriteString(quoteIfNeeded(pair.Value))
+	}
+	p.w.WriteByte('\n')
+
+	for _, pair := range nested {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(pair.Value), "    ", "  "); err != nil {
+			buf.Reset()
+			buf.WriteString(pair.Value)
+		}
+		p.w.WriteString("    ")
+		p.w.WriteString(pair.Label)
+		p.w.WriteString(": ")
+		buf.WriteTo(p.w)
+		p.w.WriteByte('\n')
+	}
+	if stack != "" {
+		for _, l := range strings.Split(stack, "\n") {
+			p.w.WriteString("    ")
+			p.w.WriteString(l)
+			p.w.WriteByte('\n')
+		}
+	}
+}
+
+func (p *printer) writeLevel(level string) {
+	c, ok := levelColors[strings.ToLower(level)]
+	if !p.color || !ok {
+		p.w.WriteString(level)
+		return
+	}
+	p.w.WriteString("\x1b[")
+	p.w.WriteString(strconv.Itoa(c))
+	p.w.WriteByte('m')
+	p.w.WriteString(level)
+	p.w.WriteString("\x1b[0m")
+}
+
+func isJSON(s string) bool {
+	if len(s) < 2 || !(s[0] == '{' || s[0] == '[') {
+		return false
+	}
+	return json.Valid([]byte(s))
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
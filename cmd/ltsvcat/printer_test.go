@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+)
+
+func TestPrinter(t *testing.T) {
+	input := "time:2017-05-03T21:09:11.983Z\tlevel:info\tcaller:main.go:1\tmsg:hello\tuser:alice\tpath:/a b\n" +
+		"time:2017-05-03T21:09:12.000Z\tlevel:error\tlogger:http\tcaller:server/handler.go:120\tmsg:failed\tusers:[{\"name\":\"a\"}]\tstacktrace:main.f\\n\\tmain.go:1\n" +
+		"not ltsv\n"
+	want := "2017-05-03T21:09:11.983Z  \x1b[34minfo\x1b[0m  main.go:1  hello user=alice path=\"/a b\"\n" +
+		"2017-05-03T21:09:12.000Z  \x1b[31merror\x1b[0m  http  server/handler.go:120  failed\n" +
+		"    users: [\n" +
+		"      {\n" +
+		"        \"name\": \"a\"\n" +
+		"      }\n" +
+		"    ]\n" +
+		"    main.f\n" +
+		"    \tmain.go:1\n" +
+		"not ltsv\n"
+
+	var out bytes.Buffer
+	p := newPrinter(&out, ltsv.NewDevelopmentEncoderConfig())
+	p.color = true
+	if err := p.printAll(bytes.NewBufferString(input)); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("got=\n%s\nwant=\n%s", got, want)
+	}
+}
+
+func TestPrinterShowHide(t *testing.T) {
+	input := "level:info\tmsg:hello\ta:1\tb:2\tc:3\n"
+	testCases := []struct {
+		show, hide []string
+		want       string
+	}{
+		{show: []string{"msg", "b"}, want: "hello b=2\n"},
+		{hide: []string{"level", "a"}, want: "hello b=2 c=3\n"},
+	}
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		p := newPrinter(&out, ltsv.NewDevelopmentEncoderConfig())
+		p.show, p.hide = tc.show, tc.hide
+		if err := p.printAll(bytes.NewBufferString(input)); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
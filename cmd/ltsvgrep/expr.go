@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap/zapcore"
+)
+
+// An expr is a compiled filter expression.
+type expr interface {
+	match(rec ltsv.Record) bool
+}
+
+type orExpr struct{ l, r expr }
+
+func (e orExpr) match(rec ltsv.Record) bool { return e.l.match(rec) || e.r.match(rec) }
+
+type andExpr struct{ l, r expr }
+
+func (e andExpr) match(rec ltsv.Record) bool { return e.l.match(rec) && e.r.match(rec) }
+
+type notExpr struct{ e expr }
+
+func (e notExpr) match(rec ltsv.Record) bool { return !e.e.match(rec) }
+
+// existsExpr matches records having the label.
+type existsExpr struct{ label string }
+
+func (e existsExpr) match(rec ltsv.Record) bool {
+	_, ok := rec.Get(e.label)
+	return ok
+}
+
+// cmpExpr compares the value of a label with a literal. Records without
+// the label never match.
+//
+// Levels are compared by severity for the level label, times
+// chronologically for the time label, and numbers and durations by their
+// value in seconds. Other values are compared as strings.
+type cmpExpr struct {
+	label string
+	op    string
+	lit   string
+	re    *regexp.Regexp
+
+	isLevel bool
+	level   zapcore.Level
+	isTime  bool
+	time    time.Time
+	isNum   bool
+	num     float64
+}
+
+func (e *cmpExpr) match(rec ltsv.Record) bool {
+	v, ok := rec.Get(e.label)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "=~":
+		return e.re.MatchString(v)
+	case "!~":
+		return !e.re.MatchString(v)
+	}
+	if e.isLevel {
+		if l, err := ltsv.TextLevelDecoder(v); err == nil {
+			return compare(e.op, int(l)-int(e.level))
+		}
+	}
+	if e.isTime {
+		if t, ok := parseTime(v); ok {
+			switch {
+			case t.Before(e.time):
+				return compare(e.op, -1)
+			case t.After(e.time):
+				return compare(e.op, 1)
+			default:
+				return compare(e.op, 0)
+			}
+		}
+	}
+	if e.isNum {
+		if n, ok := parseNumber(v); ok {
+			switch {
+			case n < e.num:
+				return compare(e.op, -1)
+			case n > e.num:
+				return compare(e.op, 1)
+			default:
+				return compare(e.op, 0)
+			}
+		}
+	}
+	return compare(e.op, strings.Compare(v, e.lit))
+}
+
+func compare(op string, c int) bool {
+	switch op {
+	case "=", "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default: // ">="
+		return c >= 0
+	}
+}
+
+// parseNumber parses a number or a duration, as written by
+// zapcore.SecondsDurationEncoder or zapcore.StringDurationEncoder, into a
+// number of seconds.
+func parseNumber(s string) (float64, bool) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), true
+	}
+	return 0, false
+}
+
+// parseTime parses a time written by zapcore.EpochTimeEncoder,
+// zapcore.ISO8601TimeEncoder or zapcore.RFC3339TimeEncoder.
+func parseTime(s string) (time.Time, bool) {
+	decoders := []ltsv.TimeDecoder{
+		ltsv.ISO8601TimeDecoder,
+		ltsv.RFC3339TimeDecoder,
+		ltsv.EpochTimeDecoder,
+	}
+	for _, dec := range decoders {
+		if t, err := dec(s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parser is a recursive descent parser for filter expressions:
+//
+//	expr       = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expr ")" | label [ op value ]
+//	op         = "=" | "==" | "!=" | "=~" | "!~" | "<" | "<=" | ">" | ">="
+//	value      = word | quoted-string
+type parser struct {
+	src      string
+	pos      int
+	levelKey string
+	timeKey  string
+}
+
+func parseExpr(src, levelKey, timeKey string) (expr, error) {
+	p := &parser{src: src, levelKey: levelKey, timeKey: timeKey}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return e, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes tok if it comes next.
+func (p *parser) accept(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orExpr{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andExpr{l, r}
+	}
+	return l, nil
+}
+
+var ops = []string{"==", "=~", "!=", "!~", "<=", ">=", "=", "<", ">"}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.accept("!") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if p.accept("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("missing )")
+		}
+		return e, nil
+	}
+	label, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if p.accept(op) {
+			lit, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			return p.newCmpExpr(label, op, lit)
+		}
+	}
+	return existsExpr{label}, nil
+}
+
+// parseValue parses a quoted string or a word, which ends at a space,
+// parenthesis, quote or operator character.
+func (p *parser) parseValue() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return "", p.errorf("unterminated string")
+		}
+		s, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return "", p.errorf("invalid string: %v", err)
+		}
+		p.pos = end + 1
+		return s, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && !unicode.IsSpace(rune(p.src[p.pos])) && !strings.ContainsRune(`()"=!<>~&|`, rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos == len(p.src) {
+			return "", p.errorf("unexpected end of expression")
+		}
+		return "", p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) newCmpExpr(label, op, lit string) (expr, error) {
+	e := &cmpExpr{label: label, op: op, lit: lit}
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(lit)
+		if err != nil {
+			return nil, p.errorf("invalid regexp: %v", err)
+		}
+		e.re = re
+		return e, nil
+	}
+	if label == p.levelKey {
+		if l, err := ltsv.TextLevelDecoder(lit); err == nil {
+			e.isLevel, e.level = true, l
+		}
+	}
+	if label == p.timeKey {
+		if t, ok := parseTime(lit); ok {
+			e.isTime, e.time = true, t
+		}
+	}
+	if n, ok := parseNumber(lit); ok {
+		e.isNum, e.num = true, n
+	}
+	return e, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+)
+
+func TestExpr(t *testing.T) {
+	lines := []string{
+		"time:1493845751.983\tlevel:info\tlogger:http.server\tlatency:0.2\tmsg:ok",
+		"time:1493845752.5\tlevel:warn\tlogger:http.client\tlatency:0.7\tmsg:slow",
+		"time:2017-05-03T21:09:13.000Z\tlevel:error\tlogger:db\tlatency:1.5s\tmsg:failed",
+		"level:debug\tmsg:no latency",
+	}
+	testCases := []struct {
+		expr string
+		want []int
+	}{
+		{`level>=warn`, []int{1, 2}},
+		{`level>=warn && logger=~"^http" && latency>0.5`, []int{1}},
+		{`latency>500ms`, []int{1, 2}},
+		{`latency`, []int{0, 1, 2}},
+		{`!latency || msg="ok"`, []int{0, 3}},
+		{`(level=info || level=debug) && !(msg!~"^no")`, []int{3}},
+		{`time>=2017-05-03T21:09:12Z`, []int{1, 2}},
+		{`time<1493845752`, []int{0}},
+		{`logger==db`, []int{2}},
+		{`msg>"p"`, []int{1}},
+	}
+	var recs []ltsv.Record
+	for _, l := range lines {
+		rec, err := ltsv.ParseRecord([]byte(l), ltsv.JSONEscape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	for _, tc := range testCases {
+		e, err := parseExpr(tc.expr, "level", "time")
+		if err != nil {
+			t.Errorf("expr=%s, unexpected error: %v", tc.expr, err)
+			continue
+		}
+		var got []int
+		for i, rec := range recs {
+			if e.match(rec) {
+				got = append(got, i)
+			}
+		}
+		if !equalInts(got, tc.want) {
+			t.Errorf("expr=%s, got=%v, want=%v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`level>=`,
+		`(level=info`,
+		`msg="abc`,
+		`msg=~"("`,
+		`a=1 b=2`,
+	} {
+		if _, err := parseExpr(src, "level", "time"); err == nil {
+			t.Errorf("expr=%s, want error", src)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	input := "level:info\tmsg:a\tuser:alice\n" +
+		"broken\n" +
+		"level:error\tmsg:b\\tc\tuser:bob\n"
+	e, err := parseExpr(`user=~"^(alice|bob)$"`, "level", "time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	g := &grep{expr: e, only: []string{"msg", "user"}, w: bufio.NewWriter(&out)}
+	if err := g.run(strings.NewReader(input), "test"); err != nil {
+		t.Fatal(err)
+	}
+	g.w.Flush()
+	if got, want := out.String(), "msg:a\tuser:alice\nmsg:b\\tc\tuser:bob\n"; got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+	if g.matches != 2 {
+		t.Errorf("got %d matches, want 2", g.matches)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
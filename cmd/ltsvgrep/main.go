@@ -0,0 +1,199 @@
+// Command ltsvgrep prints the LTSV lines matching an expression over labels.
+//
+// Usage:
+//
+//	ltsvgrep [flags] expression [file ...]
+//
+// For example:
+//
+//	ltsvgrep 'level>=warn && logger=~"^http" && latency>0.5' app.log
+//
+// An expression compares labels with literals using =, ==, !=, <, <=, >,
+// >=, =~ and !~ (regular expression match), and combines the comparisons
+// with &&, || and !. A label on its own matches lines which have it.
+// Levels are compared by severity and times chronologically. Numbers and
+// durations written by zapcore.SecondsDurationEncoder or
+// zapcore.StringDurationEncoder are compared as seconds. Lines without the
+// label in a comparison do not match it.
+//
+// Lines are read one at a time, so files of any size are processed in
+// constant memory.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+)
+
+func main() {
+	var (
+		only     = flag.String("o", "", "comma-separated labels to output instead of whole lines")
+		invert   = flag.Bool("v", false, "print lines which do not match")
+		count    = flag.Bool("c", false, "print only the number of matching lines")
+		escape   = flag.String("escape", "json", "escape mode of the input: json, minimal or percent")
+		levelKey = flag.String("level-key", "level", "label holding the level")
+		timeKey  = flag.String("time-key", "time", "label holding the time")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ltsvgrep [flags] expression [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	e, err := parseExpr(flag.Arg(0), *levelKey, *timeKey)
+	if err != nil {
+		fatal(fmt.Errorf("invalid expression: %v", err))
+	}
+	g := &grep{
+		expr:   e,
+		invert: *invert,
+		count:  *count,
+		w:      bufio.NewWriter(os.Stdout),
+	}
+	if err := g.escapeMode.UnmarshalText([]byte(*escape)); err != nil {
+		fatal(err)
+	}
+	if *only != "" {
+		g.only = strings.Split(*only, ",")
+	}
+
+	if flag.NArg() == 1 {
+		err = g.run(os.Stdin, "-")
+	} else {
+		for _, name := range flag.Args()[1:] {
+			var f *os.File
+			f, err = os.Open(name)
+			if err != nil {
+				break
+			}
+			err = g.run(f, name)
+			f.Close()
+			if err != nil {
+				break
+			}
+		}
+	}
+	if *count {
+		fmt.Fprintln(g.w, g.matches)
+	}
+	if ferr := g.w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if g.matches == 0 {
+		os.Exit(1)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "ltsvgrep: %v\n", err)
+	os.Exit(2)
+}
+
+type grep struct {
+	expr       expr
+	escapeMode ltsv.EscapeMode
+	invert     bool
+	only       []string
+	count      bool
+	w          *bufio.Writer
+
+	matches int
+}
+
+// run filters the lines read from r. Malformed lines are reported to the
+// standard error and skipped.
+func (g *grep) run(r io.Reader, name string) error {
+	br := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Lines longer than the buffer are rare, so only they are
+			// copied and grown.
+			long := append([]byte(nil), line...)
+			for err == bufio.ErrBufferFull {
+				line, err = br.ReadSlice('\n')
+				long = append(long, line...)
+			}
+			line = long
+		}
+		if len(line) > 0 {
+			g.filter(line, name, lineNo)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (g *grep) filter(line []byte, name string, lineNo int) {
+	content := bytes.TrimRight(line, "\r\n")
+	rec, err := ltsv.ParseRecord(content, g.escapeMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ltsvgrep: %s:%d: %v\n", name, lineNo, err)
+		return
+	}
+	if g.expr.match(rec) == g.invert {
+		return
+	}
+	g.matches++
+	if g.count {
+		return
+	}
+	if g.only == nil {
+		g.w.Write(content)
+		g.w.WriteByte('\n')
+		return
+	}
+	g.writeOnly(content)
+}
+
+// writeOnly writes the fields of the selected labels as they appear in
+// the line, without unescaping their values.
+func (g *grep) writeOnly(line []byte) {
+	first := true
+	for len(line) > 0 {
+		field := line
+		if i := bytes.IndexByte(line, '\t'); i >= 0 {
+			field, line = line[:i], line[i+1:]
+		} else {
+			line = nil
+		}
+		i := bytes.IndexByte(field, ':')
+		label, err := g.escapeMode.Unescape(string(field[:i]))
+		if err != nil || !contains(g.only, label) {
+			continue
+		}
+		if !first {
+			g.w.WriteByte('\t')
+		}
+		first = false
+		g.w.Write(field)
+	}
+	g.w.WriteByte('\n')
+}
+
+func contains(a []string, s string) bool {
+	for _, e := range a {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,35 @@
 package ltsv
 
 import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-// RegisterLTSVEncoder registers the LTSV encoder.
-func RegisterLTSVEncoder() error {
-	return zap.RegisterEncoder("ltsv",
+// RegisterLTSVEncoder registers the LTSV encoder with opts as "ltsv", the
+// encoding used by NewProductionConfig and NewDevelopmentConfig.
+func RegisterLTSVEncoder(opts ...Option) error {
+	return RegisterLTSVEncoderName("ltsv", opts...)
+}
+
+// RegisterLTSVEncoderName registers the LTSV encoder with opts under name,
+// so that a zap.Config can select the options by setting Encoding to name.
+// For example:
+//
+//	ltsv.RegisterLTSVEncoderName("ltsv-flat", ltsv.Flatten(".", 0))
+//	cfg := ltsv.NewProductionConfig()
+//	cfg.Encoding = "ltsv-flat"
+//	logger, err := cfg.Build()
+func RegisterLTSVEncoderName(name string, opts ...Option) error {
+	return zap.RegisterEncoder(name,
 		func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
-			return NewLTSVEncoder(cfg), nil
+			return NewLTSVEncoder(cfg, opts...), nil
 		})
 }
 
@@ -84,3 +104,461 @@ func NewDevelopmentConfig() zap.Config {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 }
+
+// NewAccessLogEncoderConfig returns an EncoderConfig for web access logs
+// with the labels recommended at http://ltsv.org/, as written by the
+// accesslog package.
+//
+// Only the fields are written, so the time of a request is logged as a
+// field in the position recommended by ltsv.org rather than as the
+// entry time.
+func NewAccessLogEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     CLFTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// NewAccessLogConfig is a configuration for web access logs written to
+// standard output. See NewAccessLogEncoderConfig.
+func NewAccessLogConfig() zap.Config {
+	return zap.Config{
+		Level:            zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:         "ltsv",
+		EncoderConfig:    NewAccessLogEncoderConfig(),
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}
+}
+
+// encoderVariants are the options of the named encoders registered by
+// RegisterLTSVEncoderVariants.
+var encoderVariants = map[string][]Option{
+	"ltsv":        nil,
+	"ltsv-strict": {StrictKeys(), InvalidKeyPolicy(ReplaceInvalidKey)},
+	"ltsv-flat":   {Flatten(".", 0)},
+}
+
+// RegisterLTSVEncoderVariants registers the LTSV encoder under the
+// following names, so that the mode can be switched by changing Encoding
+// in a zap.Config:
+//
+//	ltsv         the default options
+//	ltsv-strict  StrictKeys with ReplaceInvalidKey
+//	ltsv-flat    Flatten with the separator "." and no depth limit
+//
+// It must not be used together with RegisterLTSVEncoder, which also
+// registers "ltsv".
+func RegisterLTSVEncoderVariants() error {
+	names := make([]string, 0, len(encoderVariants))
+	for name := range encoderVariants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := RegisterLTSVEncoderName(name, encoderVariants[name]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Config is a zap.Config with an additional "ltsv" section for the
+// options of the LTSV encoder. It can be unmarshaled from JSON or YAML,
+// for example:
+//
+//	level: info
+//	encoding: ltsv-flat
+//	outputPaths: [stderr]
+//	encoderConfig:
+//	  messageKey: msg
+//	  levelKey: level
+//	  levelEncoder: lowercase
+//	ltsv:
+//	  escape: percent
+//	  invalidKeyPolicy: replace
+//	  fieldOrder: [status, method]
+//
+// Encoding must be empty or one of the names registered by
+// RegisterLTSVEncoderVariants, whose options are applied before the ones
+// in the LTSV section. Build does not need the encoders to be registered.
+type Config struct {
+	zap.Config `yaml:",inline"`
+	LTSV       EncoderOptions `json:"ltsv" yaml:"ltsv"`
+}
+
+// EncoderOptions is the serializable form of the encoder options. Zero
+// values leave the corresponding settings unchanged.
+type EncoderOptions struct {
+	// Escape is the escape mode: "json", "minimal" or "percent".
+	Escape EscapeMode `json:"escape" yaml:"escape"`
+	// BinaryEncoding is the encoding of binary values: "base64",
+	// "base64url", "rawbase64", "hex" or "escaped".
+	BinaryEncoding BinaryEncoding `json:"binaryEncoding" yaml:"binaryEncoding"`
+	// BinaryEncodingByKey overrides BinaryEncoding for the given keys.
+	BinaryEncodingByKey map[string]BinaryEncoding `json:"binaryEncodingByKey" yaml:"binaryEncodingByKey"`
+	// FloatFormat is the format of floating-point numbers, e.g. "g" or
+	// "f3". See FloatFormat.UnmarshalText.
+	FloatFormat FloatFormat `json:"floatFormat" yaml:"floatFormat"`
+	// FloatFormatByKey overrides FloatFormat for the given keys.
+	FloatFormatByKey map[string]FloatFormat `json:"floatFormatByKey" yaml:"floatFormatByKey"`
+	// NonFiniteFloats is how NaN and infinities are written inside JSON
+	// values: "string" or "null".
+	NonFiniteFloats NonFiniteFloats `json:"nonFiniteFloats" yaml:"nonFiniteFloats"`
+	// InvalidKeyPolicy is the key policy: "panic", "replace", "escape",
+	// "drop" or "fallback".
+	InvalidKeyPolicy KeyPolicy `json:"invalidKeyPolicy" yaml:"invalidKeyPolicy"`
+	// FallbackKey is the label used by the "fallback" key policy.
+	FallbackKey string `json:"fallbackKey" yaml:"fallbackKey"`
+	// StrictKeys restricts labels to the ltsv.org charset.
+	StrictKeys bool `json:"strictKeys" yaml:"strictKeys"`
+	// SpacedJSON adds spaces in nested JSON values.
+	SpacedJSON bool `json:"spacedJSON" yaml:"spacedJSON"`
+	// Flatten writes nested values as separate labels.
+	Flatten bool `json:"flatten" yaml:"flatten"`
+	// FlattenSeparator joins the keys of flattened labels. It defaults
+	// to ".".
+	FlattenSeparator string `json:"flattenSeparator" yaml:"flattenSeparator"`
+	// FlattenMaxDepth limits the depth of flattening. Zero means no limit.
+	FlattenMaxDepth int `json:"flattenMaxDepth" yaml:"flattenMaxDepth"`
+	// ReflectStructs writes reflected structs as separate labels.
+	ReflectStructs bool `json:"reflectStructs" yaml:"reflectStructs"`
+	// StructSeparator joins the key of a reflected struct and the labels
+	// of its fields. It defaults to ".".
+	StructSeparator string `json:"structSeparator" yaml:"structSeparator"`
+	// LevelFormat overrides the level encoder of the EncoderConfig:
+	// "config", "syslog" or "short".
+	LevelFormat LevelFormat `json:"levelFormat" yaml:"levelFormat"`
+	// SeverityKey is the label of the syslog severity written along with
+	// the level, e.g. "severity".
+	SeverityKey string `json:"severityKey" yaml:"severityKey"`
+	// FieldOrder lists the keys of fields to write first.
+	FieldOrder []string `json:"fieldOrder" yaml:"fieldOrder"`
+	// SplitCaller writes the caller as separate file, line and function
+	// labels.
+	SplitCaller bool `json:"splitCaller" yaml:"splitCaller"`
+	// CallerFileKey, CallerLineKey and CallerFuncKey are the labels of
+	// SplitCaller. They default to "caller_file", "caller_line" and
+	// "caller_func".
+	CallerFileKey string `json:"callerFileKey" yaml:"callerFileKey"`
+	CallerLineKey string `json:"callerLineKey" yaml:"callerLineKey"`
+	CallerFuncKey string `json:"callerFuncKey" yaml:"callerFuncKey"`
+	// TrimCallerPrefixes lists prefixes to remove from caller files.
+	TrimCallerPrefixes []string `json:"trimCallerPrefixes" yaml:"trimCallerPrefixes"`
+	// TrimCallerToModule writes caller files relative to the Go module
+	// with this path, or to the main module if it is "main".
+	TrimCallerToModule string `json:"trimCallerToModule" yaml:"trimCallerToModule"`
+	// Stacktrace is the stacktrace mode: "string", "joined", "labels" or
+	// "json".
+	Stacktrace StacktraceMode `json:"stacktrace" yaml:"stacktrace"`
+	// StacktraceSeparator joins frames in the "joined" mode. It defaults
+	// to " | ".
+	StacktraceSeparator string `json:"stacktraceSeparator" yaml:"stacktraceSeparator"`
+	// StacktraceMaxFrames limits the number of frames.
+	StacktraceMaxFrames int `json:"stacktraceMaxFrames" yaml:"stacktraceMaxFrames"`
+	// StacktraceFilter lists the function prefixes of frames to drop.
+	StacktraceFilter []string `json:"stacktraceFilter" yaml:"stacktraceFilter"`
+	// StructuredErrors enables the error format of ErrorType,
+	// ErrorCauses, ErrorMaxCauses and ErrorVerbose.
+	StructuredErrors bool `json:"structuredErrors" yaml:"structuredErrors"`
+	// ErrorType writes the Go type of errors.
+	ErrorType bool `json:"errorType" yaml:"errorType"`
+	// ErrorCauses writes the messages of wrapped errors.
+	ErrorCauses bool `json:"errorCauses" yaml:"errorCauses"`
+	// ErrorMaxCauses limits the number of causes.
+	ErrorMaxCauses int `json:"errorMaxCauses" yaml:"errorMaxCauses"`
+	// ErrorVerbose writes the verbose form of errors.
+	ErrorVerbose bool `json:"errorVerbose" yaml:"errorVerbose"`
+	// MaxValueBytes limits the size of each value.
+	MaxValueBytes int `json:"maxValueBytes" yaml:"maxValueBytes"`
+	// MaxValueBytesByKey overrides MaxValueBytes for the given labels.
+	MaxValueBytesByKey map[string]int `json:"maxValueBytesByKey" yaml:"maxValueBytesByKey"`
+	// MaxLineBytes limits the size of each line.
+	MaxLineBytes int `json:"maxLineBytes" yaml:"maxLineBytes"`
+	// OnOverflow is the action for values exceeding a limit: "truncate",
+	// "drop" or "label".
+	OnOverflow OverflowAction `json:"onOverflow" yaml:"onOverflow"`
+	// OverflowKey is the label used by the "label" action.
+	OverflowKey string `json:"overflowKey" yaml:"overflowKey"`
+	// RedactKeys lists the patterns of keys to redact.
+	RedactKeys []string `json:"redactKeys" yaml:"redactKeys"`
+	// RedactValues lists the regular expressions of values to redact.
+	RedactValues []string `json:"redactValues" yaml:"redactValues"`
+	// RedactionMask replaces redacted values. It defaults to "[REDACTED]".
+	RedactionMask string `json:"redactionMask" yaml:"redactionMask"`
+	// RedactionRevealLast is the number of characters of redacted strings
+	// to keep after the mask.
+	RedactionRevealLast int `json:"redactionRevealLast" yaml:"redactionRevealLast"`
+}
+
+// Validate reports the first problem found in the options.
+func (o EncoderOptions) Validate() error {
+	if o.Escape < JSONEscape || o.Escape > PercentEscape {
+		return fmt.Errorf("ltsv: unknown escape mode %d", int(o.Escape))
+	}
+	if o.BinaryEncoding < StdBase64Binary || o.BinaryEncoding > EscapedBinary {
+		return fmt.Errorf("ltsv: unknown binary encoding %d", int(o.BinaryEncoding))
+	}
+	if !o.FloatFormat.valid() {
+		return fmt.Errorf("ltsv: invalid floatFormat %q", o.FloatFormat)
+	}
+	for key, f := range o.FloatFormatByKey {
+		if !f.valid() {
+			return fmt.Errorf("ltsv: invalid floatFormatByKey %q for %q", f, key)
+		}
+	}
+	if o.NonFiniteFloats < NonFiniteString || o.NonFiniteFloats > NonFiniteNull {
+		return fmt.Errorf("ltsv: unknown non-finite float policy %d", int(o.NonFiniteFloats))
+	}
+	if o.LevelFormat < ConfiguredLevel || o.LevelFormat > ShortLevel {
+		return fmt.Errorf("ltsv: unknown level format %d", int(o.LevelFormat))
+	}
+	if o.InvalidKeyPolicy < PanicOnInvalidKey || o.InvalidKeyPolicy > FallbackInvalidKey {
+		return fmt.Errorf("ltsv: unknown key policy %d", int(o.InvalidKeyPolicy))
+	}
+	if o.Stacktrace < StacktraceString || o.Stacktrace > StacktraceJSON {
+		return fmt.Errorf("ltsv: unknown stacktrace mode %d", int(o.Stacktrace))
+	}
+	if o.OnOverflow < TruncateOverflow || o.OnOverflow > OverflowToLabel {
+		return fmt.Errorf("ltsv: unknown overflow action %d", int(o.OnOverflow))
+	}
+	if o.FlattenMaxDepth < 0 {
+		return fmt.Errorf("ltsv: negative flattenMaxDepth %d", o.FlattenMaxDepth)
+	}
+	for _, pattern := range o.RedactKeys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("ltsv: invalid redactKeys pattern %q", pattern)
+		}
+	}
+	for _, expr := range o.RedactValues {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("ltsv: invalid redactValues pattern: %v", err)
+		}
+	}
+	enc := &ltsvEncoder{opts: newEncoderOptions(o.Options())}
+	if o.FallbackKey != "" && !enc.validLabel(o.FallbackKey) {
+		return fmt.Errorf("ltsv: invalid fallbackKey %q", o.FallbackKey)
+	}
+	if o.OverflowKey != "" && !enc.validLabel(o.OverflowKey) {
+		return fmt.Errorf("ltsv: invalid overflowKey %q", o.OverflowKey)
+	}
+	return nil
+}
+
+// Options returns the options for NewLTSVEncoder.
+func (o EncoderOptions) Options() []Option {
+	var opts []Option
+	if o.Escape != JSONEscape {
+		opts = append(opts, ValueEscaping(o.Escape))
+	}
+	if o.BinaryEncoding != StdBase64Binary {
+		opts = append(opts, BinaryEncoder(o.BinaryEncoding))
+	}
+	if len(o.BinaryEncodingByKey) > 0 {
+		opts = append(opts, BinaryEncoderByKey(o.BinaryEncodingByKey))
+	}
+	if !o.FloatFormat.isDefault() {
+		opts = append(opts, FloatFormatting(o.FloatFormat))
+	}
+	if len(o.FloatFormatByKey) > 0 {
+		opts = append(opts, FloatFormattingByKey(o.FloatFormatByKey))
+	}
+	if o.NonFiniteFloats != NonFiniteString {
+		opts = append(opts, NonFiniteFloatsInJSON(o.NonFiniteFloats))
+	}
+	if e := o.LevelFormat.encoder(); e != nil {
+		opts = append(opts, LevelEncoder(e))
+	}
+	if o.SeverityKey != "" {
+		opts = append(opts, SeverityKey(o.SeverityKey))
+	}
+	if o.InvalidKeyPolicy != PanicOnInvalidKey {
+		opts = append(opts, InvalidKeyPolicy(o.InvalidKeyPolicy))
+	}
+	if o.FallbackKey != "" {
+		opts = append(opts, FallbackKey(o.FallbackKey))
+	}
+	if o.StrictKeys {
+		opts = append(opts, StrictKeys())
+	}
+	if o.SpacedJSON {
+		opts = append(opts, SpacedJSON())
+	}
+	if o.Flatten {
+		sep := o.FlattenSeparator
+		if sep == "" {
+			sep = "."
+		}
+		opts = append(opts, Flatten(sep, o.FlattenMaxDepth))
+	}
+	if o.ReflectStructs {
+		sep := o.StructSeparator
+		if sep == "" {
+			sep = "."
+		}
+		opts = append(opts, ReflectStructs(sep))
+	}
+	if len(o.FieldOrder) > 0 {
+		opts = append(opts, FieldOrder(o.FieldOrder...))
+	}
+	if o.SplitCaller {
+		opts = append(opts, SplitCaller(o.CallerFileKey, o.CallerLineKey, o.CallerFuncKey))
+	}
+	if len(o.TrimCallerPrefixes) > 0 {
+		opts = append(opts, TrimCallerPrefixes(o.TrimCallerPrefixes...))
+	}
+	if o.TrimCallerToModule == "main" {
+		opts = append(opts, TrimCallerToModule(""))
+	} else if o.TrimCallerToModule != "" {
+		opts = append(opts, TrimCallerToModule(o.TrimCallerToModule))
+	}
+	if o.Stacktrace != StacktraceString {
+		opts = append(opts, Stacktrace(o.Stacktrace))
+	}
+	if o.StacktraceSeparator != "" {
+		opts = append(opts, StacktraceSeparator(o.StacktraceSeparator))
+	}
+	if o.StacktraceMaxFrames > 0 {
+		opts = append(opts, StacktraceMaxFrames(o.StacktraceMaxFrames))
+	}
+	if len(o.StacktraceFilter) > 0 {
+		opts = append(opts, StacktraceFilter(o.StacktraceFilter...))
+	}
+	if o.StructuredErrors {
+		opts = append(opts, StructuredErrors(ErrorFormat{
+			Type:      o.ErrorType,
+			Causes:    o.ErrorCauses,
+			MaxCauses: o.ErrorMaxCauses,
+			Verbose:   o.ErrorVerbose,
+		}))
+	}
+	if o.MaxValueBytes > 0 {
+		opts = append(opts, MaxValueBytes(o.MaxValueBytes))
+	}
+	if len(o.MaxValueBytesByKey) > 0 {
+		opts = append(opts, MaxValueBytesByKey(o.MaxValueBytesByKey))
+	}
+	if o.MaxLineBytes > 0 {
+		opts = append(opts, MaxLineBytes(o.MaxLineBytes))
+	}
+	if o.OnOverflow != TruncateOverflow {
+		opts = append(opts, OnOverflow(o.OnOverflow))
+	}
+	if o.OverflowKey != "" {
+		opts = append(opts, OverflowKey(o.OverflowKey))
+	}
+	if len(o.RedactKeys) > 0 {
+		opts = append(opts, RedactKeys(o.RedactKeys...))
+	}
+	for _, expr := range o.RedactValues {
+		// Invalid expressions are reported by Validate.
+		if re, err := regexp.Compile(expr); err == nil {
+			opts = append(opts, RedactValues(re))
+		}
+	}
+	if o.RedactionMask != "" || o.RedactionRevealLast > 0 {
+		mask := o.RedactionMask
+		if mask == "" {
+			mask = DefaultRedactionMask
+		}
+		opts = append(opts, RedactionMask(mask, o.RedactionRevealLast))
+	}
+	return opts
+}
+
+// Validate reports the first problem found in the configuration.
+func (cfg Config) Validate() error {
+	if cfg.Level == (zap.AtomicLevel{}) {
+		return errors.New("ltsv: missing Level")
+	}
+	if _, ok := encoderVariants[cfg.encoding()]; !ok {
+		return fmt.Errorf("ltsv: unknown encoding %q", cfg.Encoding)
+	}
+	return cfg.LTSV.Validate()
+}
+
+func (cfg Config) encoding() string {
+	if cfg.Encoding == "" {
+		return "ltsv"
+	}
+	return cfg.Encoding
+}
+
+// Build validates the configuration and builds a logger with the LTSV
+// encoder, in the same way as zap.Config.Build does. If Development is set,
+// invalid keys are also reported to the error output with
+// KeyWarningOutput.
+func (cfg Config) Build(opts ...zap.Option) (*zap.Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	sink, closeOut, err := zap.Open(cfg.OutputPaths...)
+	if err != nil {
+		return nil, err
+	}
+	errSink, _, err := zap.Open(cfg.ErrorOutputPaths...)
+	if err != nil {
+		closeOut()
+		return nil, err
+	}
+
+	encOpts := append(append([]Option(nil), encoderVariants[cfg.encoding()]...), cfg.LTSV.Options()...)
+	if cfg.Development {
+		// Report invalid keys, which are easily missed otherwise.
+		encOpts = append(encOpts, KeyWarningOutput(errSink))
+	}
+	enc := NewLTSVEncoder(cfg.EncoderConfig, encOpts...)
+
+	log := zap.New(
+		zapcore.NewCore(enc, sink, cfg.Level),
+		append(cfg.buildOptions(errSink), opts...)...,
+	)
+	return log, nil
+}
+
+func (cfg Config) buildOptions(errSink zapcore.WriteSyncer) []zap.Option {
+	opts := []zap.Option{zap.ErrorOutput(errSink)}
+
+	if cfg.Development {
+		opts = append(opts, zap.Development())
+	}
+
+	if !cfg.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+
+	stackLevel := zap.ErrorLevel
+	if cfg.Development {
+		stackLevel = zap.WarnLevel
+	}
+	if !cfg.DisableStacktrace {
+		opts = append(opts, zap.AddStacktrace(stackLevel))
+	}
+
+	if scfg := cfg.Sampling; scfg != nil {
+		opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			var samplerOpts []zapcore.SamplerOption
+			if scfg.Hook != nil {
+				samplerOpts = append(samplerOpts, zapcore.SamplerHook(scfg.Hook))
+			}
+			return zapcore.NewSamplerWithOptions(core, time.Second, scfg.Initial, scfg.Thereafter, samplerOpts...)
+		}))
+	}
+
+	if len(cfg.InitialFields) > 0 {
+		fs := make([]zap.Field, 0, len(cfg.InitialFields))
+		keys := make([]string, 0, len(cfg.InitialFields))
+		for k := range cfg.InitialFields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fs = append(fs, zap.Any(k, cfg.InitialFields[k]))
+		}
+		opts = append(opts, zap.Fields(fs...))
+	}
+
+	return opts
+}
@@ -0,0 +1,194 @@
+package ltsv_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigBuild(t *testing.T) {
+	testCases := []struct {
+		json string
+		want string
+	}{
+		{
+			json: `{"level": "info", "encoding": "ltsv-flat", "encoderConfig": {"messageKey": "msg"},
+				"ltsv": {"invalidKeyPolicy": "replace", "fieldOrder": ["b"]}}`,
+			want: "msg:hello\tb:2\treq.path:/\thost_port:x\n",
+		},
+		{
+			json: `{"level": "info", "encoderConfig": {"messageKey": "msg"},
+				"ltsv": {"escape": "percent", "invalidKeyPolicy": "drop", "flatten": true, "flattenSeparator": "_"}}`,
+			want: "msg:hello\treq_path:/\tb:2\n",
+		},
+		{
+			json: `{"level": "info", "encoding": "ltsv-strict", "encoderConfig": {"messageKey": "msg"},
+				"ltsv": {"spacedJSON": true}}`,
+			want: "msg:hello\treq:{\"path\": \"/\"}\thost_port:x\tb:2\n",
+		},
+		{
+			json: `{"level": "info", "encoderConfig": {"messageKey": "msg"},
+				"ltsv": {"invalidKeyPolicy": "replace", "maxValueBytes": 10, "onOverflow": "label"}}`,
+			want: "msg:hello\thost_port:x\tb:2\tltsvOverflow:req=12\n",
+		},
+		{
+			json: `{"level": "info", "encoding": "ltsv-strict", "encoderConfig": {"messageKey": "msg"},
+				"ltsv": {"redactKeys": ["req.path", "b"], "redactValues": ["^x$"], "redactionMask": "-"}}`,
+			want: "msg:hello\treq:{\"path\":\"-\"}\thost_port:-\tb:-\n",
+		},
+	}
+	for _, tc := range testCases {
+		var cfg ltsv.Config
+		if err := json.Unmarshal([]byte(tc.json), &cfg); err != nil {
+			t.Fatalf("failed to unmarshal config; json=%s, err=%v", tc.json, err)
+		}
+		path := filepath.Join(t.TempDir(), "app.log")
+		cfg.OutputPaths = []string{path}
+		logger, err := cfg.Build()
+		if err != nil {
+			t.Fatalf("failed to build logger; json=%s, err=%v", tc.json, err)
+		}
+		logger.Info("hello", zap.Dict("req", zap.String("path", "/")), zap.String("host:port", "x"), zap.Int("b", 2))
+		logger.Sync()
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("got=%q, want=%q, json=%s", got, tc.want, tc.json)
+		}
+	}
+}
+
+func TestConfigBuildYAML(t *testing.T) {
+	testCases := []struct {
+		yaml string
+		want string
+	}{
+		{
+			yaml: `
+level: info
+encoding: ltsv-flat
+encoderConfig:
+  messageKey: msg
+ltsv:
+  invalidKeyPolicy: replace
+  fieldOrder: [b]
+`,
+			want: "msg:hello\tb:2\treq.path:/\thost_port:x\n",
+		},
+		{
+			yaml: `
+level: info
+encoderConfig:
+  messageKey: msg
+ltsv:
+  escape: percent
+  invalidKeyPolicy: drop
+  flatten: true
+  flattenSeparator: _
+`,
+			want: "msg:hello\treq_path:/\tb:2\n",
+		},
+		{
+			yaml: `
+level: info
+encoderConfig:
+  messageKey: msg
+ltsv:
+  invalidKeyPolicy: replace
+  maxValueBytes: 10
+  onOverflow: label
+`,
+			want: "msg:hello\thost_port:x\tb:2\tltsvOverflow:req=12\n",
+		},
+	}
+	for _, tc := range testCases {
+		var cfg ltsv.Config
+		if err := yaml.Unmarshal([]byte(tc.yaml), &cfg); err != nil {
+			t.Fatalf("failed to unmarshal config; yaml=%s, err=%v", tc.yaml, err)
+		}
+		path := filepath.Join(t.TempDir(), "app.log")
+		cfg.OutputPaths = []string{path}
+		logger, err := cfg.Build()
+		if err != nil {
+			t.Fatalf("failed to build logger; yaml=%s, err=%v", tc.yaml, err)
+		}
+		logger.Info("hello", zap.Dict("req", zap.String("path", "/")), zap.String("host:port", "x"), zap.Int("b", 2))
+		logger.Sync()
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("got=%q, want=%q, yaml=%s", got, tc.want, tc.yaml)
+		}
+	}
+
+	var cfg ltsv.Config
+	err := yaml.Unmarshal([]byte("level: info\nltsv:\n  onOverflow: wrap\n"), &cfg)
+	if err == nil {
+		t.Errorf("got no error for unknown overflow action, want error")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []string{
+		`{"encoding": "ltsv"}`,
+		`{"level": "info", "encoding": "json"}`,
+		`{"level": "info", "ltsv": {"flattenMaxDepth": -1}}`,
+		`{"level": "info", "ltsv": {"fallbackKey": "a:b"}}`,
+		`{"level": "info", "ltsv": {"overflowKey": "a:b"}}`,
+		`{"level": "info", "ltsv": {"redactKeys": ["["]}}`,
+		`{"level": "info", "ltsv": {"redactValues": ["("]}}`,
+		`{"level": "info", "ltsv": {"strictKeys": true, "fallbackKey": "a b"}}`,
+	}
+	for _, s := range testCases {
+		var cfg ltsv.Config
+		if err := json.Unmarshal([]byte(s), &cfg); err != nil {
+			t.Fatalf("failed to unmarshal config; json=%s, err=%v", s, err)
+		}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("got no error, want error; json=%s", s)
+		}
+	}
+
+	var cfg ltsv.Config
+	err := json.Unmarshal([]byte(`{"level": "info", "ltsv": {"escape": "base64"}}`), &cfg)
+	if err == nil {
+		t.Errorf("got no error for unknown escape mode, want error")
+	}
+}
+
+func TestConfigBuildDevelopmentKeyWarnings(t *testing.T) {
+	for _, development := range []bool{false, true} {
+		cfg := ltsv.Config{Config: ltsv.NewProductionConfig()}
+		cfg.Development = development
+		cfg.LTSV.InvalidKeyPolicy = ltsv.ReplaceInvalidKey
+		dir := t.TempDir()
+		cfg.OutputPaths = []string{filepath.Join(dir, "app.log")}
+		cfg.ErrorOutputPaths = []string{filepath.Join(dir, "error.log")}
+		logger, err := cfg.Build()
+		if err != nil {
+			t.Fatalf("failed to build logger; err=%v", err)
+		}
+		logger.Info("hello", zap.String("host:port", "x"))
+		logger.Sync()
+
+		got, err := os.ReadFile(cfg.ErrorOutputPaths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if warned := strings.Contains(string(got), `ltsv: invalid key "host:port"`); warned != development {
+			t.Errorf("development=%v, got error output %q", development, got)
+		}
+	}
+}
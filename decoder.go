@@ -0,0 +1,197 @@
+package ltsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Pair is a label and its value in a record.
+type Pair struct {
+	Label string
+	Value string
+
+	// JSON holds the decoded value if the Decoder has DecodeJSON set and
+	// Value is a JSON object or array, as written by the encoder for
+	// nested values. Numbers are decoded as json.Number.
+	JSON interface{}
+}
+
+// A Record is the ordered list of label and value pairs in an LTSV line.
+type Record []Pair
+
+// Get returns the value of the first pair with the label.
+func (r Record) Get(label string) (string, bool) {
+	for i := range r {
+		if r[i].Label == label {
+			return r[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// A SyntaxError describes a malformed LTSV line.
+type SyntaxError struct {
+	Line int // line number, starting at 1
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("ltsv: line %d: %s", e.Line, e.Msg)
+}
+
+// A Decoder reads records from an LTSV stream, one per line.
+//
+// Decoding stops at the first malformed line and Err returns a
+// *SyntaxError. To skip malformed lines instead, read the lines yourself
+// and call ParseRecord on each.
+type Decoder struct {
+	// EscapeMode is the mode the labels and values were escaped with.
+	// It is JSONEscape by default, like the encoder's.
+	EscapeMode EscapeMode
+
+	// DecodeJSON makes the decoder set Pair.JSON for values which are JSON
+	// objects or arrays.
+	DecodeJSON bool
+
+	r    *bufio.Reader
+	buf  []byte
+	rec  Record
+	line int
+	err  error
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Scan advances the decoder to the next record, which will then be
+// available through the Record method. It returns false when the scan
+// stops, either by reaching the end of the input or an error.
+// An empty line results in an empty record.
+func (d *Decoder) Scan() bool {
+	if d.err != nil {
+		return false
+	}
+	line, err := d.readLine()
+	if err != nil {
+		if err != io.EOF || len(line) == 0 {
+			d.rec = nil
+			d.err = err
+			return false
+		}
+	}
+	d.line++
+	rec, err := ParseRecord(line, d.EscapeMode)
+	if err != nil {
+		d.rec = nil
+		d.err = &SyntaxError{Line: d.line, Msg: err.Error()}
+		return false
+	}
+	if d.DecodeJSON {
+		decodeJSONValues(rec)
+	}
+	d.rec = rec
+	return true
+}
+
+// Record returns the most recent record read by a call to Scan.
+func (d *Decoder) Record() Record {
+	return d.rec
+}
+
+// Line returns the line number of the most recent record, starting at 1.
+func (d *Decoder) Line() int {
+	return d.line
+}
+
+// Err returns the first non-EOF error that was encountered by the decoder.
+func (d *Decoder) Err() error {
+	if d.err == io.EOF {
+		return nil
+	}
+	return d.err
+}
+
+// readLine reads a line of any length without the trailing newline.
+// The returned slice is valid until the next call.
+func (d *Decoder) readLine() ([]byte, error) {
+	d.buf = d.buf[:0]
+	for {
+		chunk, err := d.r.ReadSlice('\n')
+		d.buf = append(d.buf, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil {
+			d.buf = d.buf[:len(d.buf)-1]
+		}
+		return bytes.TrimSuffix(d.buf, []byte{'\r'}), err
+	}
+}
+
+// ParseRecord parses a line without the trailing newline into a record,
+// unescaping labels and values with mode. Values which are JSON objects or
+// arrays are kept as they are.
+func ParseRecord(line []byte, mode EscapeMode) (Record, error) {
+	if len(line) == 0 {
+		return Record{}, nil
+	}
+	rec := make(Record, 0, bytes.Count(line, []byte{'\t'})+1)
+	for len(line) > 0 {
+		field := line
+		if i := bytes.IndexByte(line, '\t'); i >= 0 {
+			field, line = line[:i], line[i+1:]
+		} else {
+			line = nil
+		}
+		i := bytes.IndexByte(field, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("missing colon in field %d", len(rec)+1)
+		}
+		label, err := mode.Unescape(string(field[:i]))
+		if err != nil {
+			return nil, fmt.Errorf("label of field %d: %v", len(rec)+1, err)
+		}
+		raw := field[i+1:]
+		if isJSONValue(raw) {
+			// Nested values are written as JSON without further escaping.
+			rec = append(rec, Pair{Label: label, Value: string(raw)})
+			continue
+		}
+		value, err := mode.Unescape(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("value of label %q: %v", label, err)
+		}
+		rec = append(rec, Pair{Label: label, Value: value})
+	}
+	return rec, nil
+}
+
+// isJSONValue reports whether b is a JSON object or array.
+func isJSONValue(b []byte) bool {
+	if len(b) < 2 || !(b[0] == '{' && b[len(b)-1] == '}' || b[0] == '[' && b[len(b)-1] == ']') {
+		return false
+	}
+	return json.Valid(b)
+}
+
+// decodeJSONValues sets Pair.JSON for values which are JSON objects or
+// arrays.
+func decodeJSONValues(rec Record) {
+	for i := range rec {
+		if !isJSONValue([]byte(rec[i].Value)) {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(rec[i].Value))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err == nil {
+			rec[i].JSON = v
+		}
+	}
+}
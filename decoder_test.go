@@ -0,0 +1,102 @@
+package ltsv_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDecoder(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+	enc := ltsv.NewLTSVEncoder(cfg)
+
+	var input strings.Builder
+	buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: "a\tb"}, []zapcore.Field{
+		zap.String("url", "http://example.com/?q=\"x\""),
+		zap.Array("users", users{jane}),
+		zap.String("text", "[not json"),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode entry; err=%+v", err)
+	}
+	input.WriteString(buf.String())
+	input.WriteString("\r\n")
+	input.WriteString("long:" + strings.Repeat("x", 10000))
+
+	dec := ltsv.NewDecoder(strings.NewReader(input.String()))
+	dec.DecodeJSON = true
+
+	want := []ltsv.Record{
+		{
+			{Label: "level", Value: "info"},
+			{Label: "msg", Value: "a\tb"},
+			{Label: "url", Value: "http://example.com/?q=\"x\""},
+			{
+				Label: "users",
+				Value: `[{"name":"Jane Doe","email":"jane@test.com","created_at":315576000000000000}]`,
+				JSON: []interface{}{map[string]interface{}{
+					"name":       "Jane Doe",
+					"email":      "jane@test.com",
+					"created_at": json.Number("315576000000000000"),
+				}},
+			},
+			{Label: "text", Value: "[not json"},
+		},
+		{},
+		{
+			{Label: "long", Value: strings.Repeat("x", 10000)},
+		},
+	}
+	var got []ltsv.Record
+	for dec.Scan() {
+		got = append(got, dec.Record())
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got=%+v, want=%+v", got, want)
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	testCases := []struct {
+		mode  ltsv.EscapeMode
+		input string
+		want  string
+	}{
+		{
+			input: "a:1\nb:2\tnocolon\n",
+			want:  "ltsv: line 2: missing colon in field 2",
+		},
+		{
+			input: "a:\\x\n",
+			want:  `ltsv: line 1: value of label "a": ltsv: invalid JSON escape`,
+		},
+		{
+			mode:  ltsv.PercentEscape,
+			input: "a:1\n\nb%zz:2\n",
+			want:  "ltsv: line 3: label of field 1: ltsv: invalid percent escape",
+		},
+	}
+	for _, tc := range testCases {
+		dec := ltsv.NewDecoder(strings.NewReader(tc.input))
+		dec.EscapeMode = tc.mode
+		for dec.Scan() {
+		}
+		err := dec.Err()
+		if _, ok := err.(*ltsv.SyntaxError); !ok {
+			t.Errorf("input=%q, got=%v, want *SyntaxError", tc.input, err)
+			continue
+		}
+		if err.Error() != tc.want {
+			t.Errorf("input=%q, got=%q, want=%q", tc.input, err.Error(), tc.want)
+		}
+	}
+}
@@ -3,11 +3,69 @@
 // https://github.com/uber-go/zap for the zap logging library.
 //
 // Keys and values are escaped in the same way as JSON strings'
-// content (without enclosing double qoutes).
+// content (without enclosing double qoutes) by default.
+// The ValueEscaping option selects a minimal or percent-encoding escape
+// mode instead, and EscapeMode.Unescape reverses each of them.
 //
-// The LTSV encoder panics if a key contains colon ':'.
-// Values can contain colon characters.
+// By default the LTSV encoder panics if a key contains colon ':'.
+// Use the InvalidKeyPolicy option to replace, escape or drop such keys
+// instead. Values can contain colon characters. The StrictKeys option
+// restricts labels further to the ltsv.org charset [0-9A-Za-z_.-].
 //
 // Nested values (like structs, objects, arrays) are encoded in JSON format.
 // See Example (Nested) or Example (Reflected).
+// With the Flatten option, namespaces, objects and arrays are written as
+// separate labels like "http.method" or "users.0.name" instead.
+// With the ReflectStructs option, reflected structs are written as one
+// label per field, named by the "ltsv" struct tag.
+// Other reflected values are written with NewReflectedEncoder of the
+// EncoderConfig, or with the ReflectedEncoder option, if set.
+//
+// Options are passed to NewLTSVEncoder, or to RegisterLTSVEncoder and
+// RegisterLTSVEncoderName to use them from a zap.Config by encoding name.
+// Config extends zap.Config with an "ltsv" section to set them in JSON or
+// YAML configuration files.
+//
+// Floating-point numbers are written without an exponent by default, and
+// NaN and infinities as NaN, +Inf and -Inf without quotes at the top level.
+// FloatFormatting, FloatFormattingByKey and NonFiniteFloatsInJSON change
+// this.
+//
+// Binary values are written in base64 by default. BinaryEncoder and
+// BinaryEncoderByKey select base64url, unpadded base64, hex or escaped
+// bytes instead.
+//
+// MaxValueBytes and MaxLineBytes limit the size of values and lines.
+// Values over a limit are truncated with a marker, dropped or moved to an
+// overflow label, as selected with OnOverflow.
+//
+// RedactKeys and RedactValues mask sensitive values, selected by their key
+// or by regular expressions, wherever they appear in the line.
+// Pseudonymize replaces values with a keyed HMAC instead, so that they can
+// still be correlated across lines.
+//
+// FunctionKey of the EncoderConfig is supported. SplitCaller writes the
+// caller as separate file, line and function labels, and
+// TrimCallerPrefixes and TrimCallerToModule shorten the file.
+//
+// The Stacktrace option writes stacktraces as frames joined on one line,
+// as one label per frame or as a JSON array instead of a single escaped
+// value, optionally filtered with StacktraceFilter and StacktraceMaxFrames.
+//
+// StructuredErrors writes error fields with their type and the messages
+// of the errors they wrap as numbered labels, and splits their verbose
+// form into frames like stacktraces.
+//
+// SyslogSeverityLevelEncoder and ShortLevelEncoder write levels as syslog
+// severities and in a short form, and SeverityKey writes the severity
+// along with the level.
+//
+// Time and duration encoders in the formats of Apache and nginx logs are
+// provided for consumers which already parse web server logs, along with
+// the NewApacheEncoderConfig and NewNginxEncoderConfig presets.
+//
+// RegisterSyslogSink registers zap sinks which send LTSV lines to a
+// syslog server in RFC 5424 or RFC 3164 messages.
+//
+// Decoder reads LTSV lines written by the encoder back into records.
 package ltsv
@@ -22,10 +22,6 @@
 package ltsv
 
 import (
-	"encoding/base64"
-	"encoding/json"
-	"math"
-	"strings"
 	"sync"
 	"time"
 	"unicode/utf8"
@@ -39,12 +35,61 @@ const hex = "0123456789abcdef"
 
 type ltsvEncoder struct {
 	*zapcore.EncoderConfig
+	opts           *encoderOptions
 	buf            *buffer.Buffer
-	spaced         bool // include spaces after colons and commas in JSON
 	openNamespaces int
 
+	// flatPrefix is prepended to top-level labels while nested values are
+	// being flattened, and flatDepth is the number of levels in it.
+	flatPrefix string
+	flatDepth  int
+
+	// redactPath is the path of the enclosing keys, each followed by a dot,
+	// for matching keys to redact or pseudonymize. It is only kept with
+	// redaction or pseudonymization.
+	redactPath string
+
+	// pseudonymized is set once a value has been replaced with a pseudonym.
+	pseudonymized bool
+
+	// lastKey is the key of the last top-level label, for recognizing the
+	// verbose form of error fields written by zap.
+	lastKey string
+
+	// reflectEnc writes reflected values to buf. It was created for
+	// reflectCfg and reflectOpts, and is dropped when the encoder is put
+	// back into the pool.
+	reflectEnc  zapcore.ReflectedEncoder
+	reflectCfg  *zapcore.EncoderConfig
+	reflectOpts *encoderOptions
+	// reflectDst, if not nil, receives the output of reflectEnc instead
+	// of buf.
+	reflectDst *buffer.Buffer
+
 	nestedLevel  int
 	justAfterKey bool
+
+	// pending is set while the value of the last top-level label, which
+	// starts at valueStart, may still grow. keyStart is where the label
+	// and its separator start. See finishValue.
+	pending    bool
+	valueJSON  bool // the pending value is written as JSON
+	valueKey   string
+	keyStart   int
+	valueStart int
+	overflowed string // labels and sizes for OverflowToLabel
+
+	// inLine is set if buf holds the line being encoded, so that the line
+	// limit applies. Context encoders created with With leave it to
+	// EncodeEntry.
+	inLine bool
+	// truncations records the values of context labels cut by the value
+	// limits, so that they can be cut again at their size in the line.
+	truncations []truncation
+	// origSize and keptSize, if origSize is not zero, are the truncation
+	// of the pending value when it was first cut.
+	origSize int
+	keptSize int
 }
 
 var bufferpool = buffer.NewPool()
@@ -59,103 +104,213 @@ func getLTSVEncoder() *ltsvEncoder {
 
 func putLTSVEncoder(enc *ltsvEncoder) {
 	enc.EncoderConfig = nil
+	enc.opts = nil
 	enc.buf = nil
-	enc.spaced = false
 	enc.openNamespaces = 0
+	enc.flatPrefix = ""
+	enc.flatDepth = 0
+	enc.redactPath = ""
+	enc.pseudonymized = false
+	enc.lastKey = ""
+	enc.reflectEnc = nil
+	enc.reflectCfg = nil
+	enc.reflectOpts = nil
+	enc.reflectDst = nil
+	enc.pending = false
+	enc.valueKey = ""
+	enc.overflowed = ""
+	enc.inLine = false
+	enc.truncations = nil
+	enc.origSize = 0
 	ltsvPool.Put(enc)
 }
 
-// NewLTSVEncoder creates a line-oriented LTSV encoder.
-func NewLTSVEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
-	return newLTSVEncoder(cfg, false)
+// NewLTSVEncoder creates a line-oriented LTSV encoder. Without options,
+// nested values are written as compact JSON with JSON escaping, and an
+// invalid key causes a panic.
+func NewLTSVEncoder(cfg zapcore.EncoderConfig, opts ...Option) zapcore.Encoder {
+	return newLTSVEncoder(cfg, opts...)
 }
 
-func newLTSVEncoder(cfg zapcore.EncoderConfig, spaced bool) *ltsvEncoder {
+func newLTSVEncoder(cfg zapcore.EncoderConfig, opts ...Option) *ltsvEncoder {
 	return &ltsvEncoder{
 		EncoderConfig: &cfg,
+		opts:          newEncoderOptions(opts),
 		buf:           bufferpool.Get(),
-		spaced:        spaced,
 	}
 }
 
 func (enc *ltsvEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
-	enc.addKey(key)
-	return enc.AppendArray(arr)
+	if enc.flattening() {
+		saved := enc.pushFlatKey(key)
+		err := arr.MarshalLogArray(&flatArrayEncoder{enc: enc})
+		enc.popFlatKey(saved)
+		return err
+	}
+	if !enc.addKey(key) {
+		return nil
+	}
+	saved := enc.pushRedactPath(key)
+	err := enc.AppendArray(arr)
+	enc.redactPath = saved
+	return err
 }
 
 func (enc *ltsvEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
-	enc.addKey(key)
-	return enc.AppendObject(obj)
+	if enc.flattening() {
+		saved := enc.pushFlatKey(key)
+		err := obj.MarshalLogObject(enc)
+		enc.popFlatKey(saved)
+		return err
+	}
+	if !enc.addKey(key) {
+		return nil
+	}
+	saved := enc.pushRedactPath(key)
+	err := enc.AppendObject(obj)
+	enc.redactPath = saved
+	return err
 }
 
 func (enc *ltsvEncoder) AddBinary(key string, val []byte) {
-	enc.AddString(key, base64.StdEncoding.EncodeToString(val))
+	if enc.addKey(key) {
+		enc.appendBinary(val, enc.opts.binaryEncodingOf(key))
+	}
 }
 
 func (enc *ltsvEncoder) AddByteString(key string, val []byte) {
-	enc.addKey(key)
-	enc.AppendByteString(val)
+	if enc.redactsKey(key) {
+		if enc.writeKey(key) {
+			enc.appendString(enc.opts.redaction.maskString(string(val)))
+		}
+		return
+	}
+	if enc.pseudonymizesKey(key) {
+		if enc.writeKey(key) {
+			enc.appendPseudonym(string(val))
+		}
+		return
+	}
+	if enc.writeKey(key) {
+		enc.AppendByteString(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddBool(key string, val bool) {
-	enc.addKey(key)
-	enc.AppendBool(val)
+	if enc.addKey(key) {
+		enc.AppendBool(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddComplex128(key string, val complex128) {
-	enc.addKey(key)
-	enc.AppendComplex128(val)
+	if enc.addKey(key) {
+		enc.AppendComplex128(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddDuration(key string, val time.Duration) {
-	enc.addKey(key)
-	enc.AppendDuration(val)
+	if enc.addKey(key) {
+		enc.AppendDuration(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddFloat64(key string, val float64) {
-	enc.addKey(key)
-	enc.AppendFloat64(val)
+	if enc.addKey(key) {
+		enc.appendFloatFormat(val, 64, enc.opts.floatFormatOf(key))
+	}
 }
 
 func (enc *ltsvEncoder) AddInt64(key string, val int64) {
-	enc.addKey(key)
-	enc.AppendInt64(val)
+	if enc.addKey(key) {
+		enc.AppendInt64(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddReflected(key string, obj interface{}) error {
-	marshaled, err := json.Marshal(obj)
-	if err != nil {
-		return err
+	if enc.reflectsStruct(obj) {
+		return enc.addStructLabels(key, obj)
 	}
-	enc.addKey(key)
-	_, err = enc.buf.Write(marshaled)
-	return err
+	return enc.addReflectedJSON(key, obj)
+}
+
+// addReflectedJSON writes obj with the reflected encoder under key. If it
+// fails, the error is written under key followed by "Error" instead.
+func (enc *ltsvEncoder) addReflectedJSON(key string, obj interface{}) error {
+	topLevel := enc.nestedLevel == 0 && enc.openNamespaces == 0
+	if topLevel {
+		enc.finishValue()
+	}
+	start := enc.buf.Len()
+	if !enc.addKey(key) {
+		return nil
+	}
+	enc.justAfterKey = false
+	if err := enc.encodeReflected(obj, enc.redactPath+key+"."); err != nil {
+		enc.truncateBuf(start)
+		if topLevel {
+			enc.pending = false
+		}
+		enc.AddString(key+"Error", err.Error())
+	}
+	return nil
 }
 
 func (enc *ltsvEncoder) OpenNamespace(key string) {
-	enc.addKey(key)
+	if enc.flattening() {
+		// Namespaces are never closed explicitly, so the prefix stays
+		// until the end of the enclosing object or entry.
+		enc.pushFlatKey(key)
+		return
+	}
+	// The namespace itself is not masked, since the fields which follow
+	// belong to it. Their keys are matched with the namespace in the path.
+	if !enc.writeKey(key) {
+		return
+	}
+	enc.pushRedactPath(key)
+	enc.justAfterKey = false
 	enc.buf.AppendByte('{')
+	enc.valueJSON = true
 	enc.openNamespaces++
 }
 
 func (enc *ltsvEncoder) AddString(key, val string) {
-	enc.addKey(key)
-	enc.AppendString(val)
+	if enc.redactsKey(key) {
+		if enc.writeKey(key) {
+			enc.appendString(enc.opts.redaction.maskString(val))
+		}
+		return
+	}
+	if enc.pseudonymizesKey(key) {
+		if enc.writeKey(key) {
+			enc.appendPseudonym(val)
+		}
+		return
+	}
+	if enc.addErrorVerbose(key, val) {
+		return
+	}
+	if enc.writeKey(key) {
+		enc.AppendString(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddTime(key string, val time.Time) {
-	enc.addKey(key)
-	enc.AppendTime(val)
+	if enc.addKey(key) {
+		enc.AppendTime(val)
+	}
 }
 
 func (enc *ltsvEncoder) AddUint64(key string, val uint64) {
-	enc.addKey(key)
-	enc.AppendUint64(val)
+	if enc.addKey(key) {
+		enc.AppendUint64(val)
+	}
 }
 
 func (enc *ltsvEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('[')
+	enc.valueJSON = true
 	enc.nestedLevel++
 	err := arr.MarshalLogArray(enc)
 	enc.nestedLevel--
@@ -166,6 +321,7 @@ func (enc *ltsvEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
 func (enc *ltsvEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
 	enc.addElementSeparator()
 	enc.buf.AppendByte('{')
+	enc.valueJSON = true
 	enc.nestedLevel++
 	err := obj.MarshalLogObject(enc)
 	enc.nestedLevel--
@@ -179,6 +335,14 @@ func (enc *ltsvEncoder) AppendBool(val bool) {
 }
 
 func (enc *ltsvEncoder) AppendByteString(val []byte) {
+	if enc.pseudonymizesPath() {
+		enc.appendPseudonym(string(val))
+		return
+	}
+	if r := enc.opts.redaction; r != nil && len(r.values) > 0 {
+		enc.appendString(r.redactString(string(val)))
+		return
+	}
 	enc.addElementSeparator()
 	if enc.nestedLevel == 0 && enc.openNamespaces == 0 {
 		enc.safeAddByteString(val)
@@ -193,18 +357,19 @@ func (enc *ltsvEncoder) AppendComplex128(val complex128) {
 	enc.addElementSeparator()
 	// Cast to a platform-independent, fixed-size type.
 	r, i := float64(real(val)), float64(imag(val))
+	f := enc.opts.floatFormat
 	if enc.nestedLevel == 0 && enc.openNamespaces == 0 {
-		enc.buf.AppendFloat(r, 64)
+		enc.addFloat(r, 64, f)
 		enc.buf.AppendByte('+')
-		enc.buf.AppendFloat(i, 64)
+		enc.addFloat(i, 64, f)
 		enc.buf.AppendByte('i')
 	} else {
 		enc.buf.AppendByte('"')
 		// Because we're always in a quoted string, we can use strconv without
 		// special-casing NaN and +/-Inf.
-		enc.buf.AppendFloat(r, 64)
+		enc.addFloat(r, 64, f)
 		enc.buf.AppendByte('+')
-		enc.buf.AppendFloat(i, 64)
+		enc.addFloat(i, 64, f)
 		enc.buf.AppendByte('i')
 		enc.buf.AppendByte('"')
 	}
@@ -226,16 +391,28 @@ func (enc *ltsvEncoder) AppendInt64(val int64) {
 }
 
 func (enc *ltsvEncoder) AppendReflected(val interface{}) error {
-	marshaled, err := json.Marshal(val)
-	if err != nil {
+	start := enc.buf.Len()
+	enc.addElementSeparator()
+	if err := enc.encodeReflected(val, enc.redactPath); err != nil {
+		enc.truncateBuf(start)
 		return err
 	}
-	enc.addElementSeparator()
-	_, err = enc.buf.Write(marshaled)
-	return err
+	return nil
 }
 
 func (enc *ltsvEncoder) AppendString(val string) {
+	if enc.pseudonymizesPath() {
+		enc.appendPseudonym(val)
+		return
+	}
+	if r := enc.opts.redaction; r != nil && len(r.values) > 0 {
+		val = r.redactString(val)
+	}
+	enc.appendString(val)
+}
+
+// appendString appends val without redacting it.
+func (enc *ltsvEncoder) appendString(val string) {
 	enc.addElementSeparator()
 	if enc.nestedLevel == 0 && enc.openNamespaces == 0 {
 		enc.safeAddString(val)
@@ -288,61 +465,69 @@ func (enc *ltsvEncoder) AppendUintptr(v uintptr)            { enc.AppendUint64(u
 func (enc *ltsvEncoder) Clone() zapcore.Encoder {
 	clone := enc.clone()
 	clone.buf.Write(enc.buf.Bytes())
+	clone.pending = enc.pending
+	clone.valueJSON = enc.valueJSON
+	clone.valueKey = enc.valueKey
+	clone.keyStart = enc.keyStart
+	clone.valueStart = enc.valueStart
+	clone.truncations = append([]truncation(nil), enc.truncations...)
 	return clone
 }
 
 func (enc *ltsvEncoder) clone() *ltsvEncoder {
 	clone := getLTSVEncoder()
 	clone.EncoderConfig = enc.EncoderConfig
-	clone.spaced = enc.spaced
+	clone.opts = enc.opts
 	clone.openNamespaces = enc.openNamespaces
+	clone.flatPrefix = enc.flatPrefix
+	clone.flatDepth = enc.flatDepth
+	clone.redactPath = enc.redactPath
+	clone.overflowed = enc.overflowed
+	clone.pseudonymized = enc.pseudonymized
 	clone.buf = bufferpool.Get()
 	return clone
 }
 
 func (enc *ltsvEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
 	final := enc.clone()
+	final.inLine = true
+
+	// Namespaces opened by With apply to the context and fields only, so
+	// entry metadata is always written as plain top-level labels.
+	final.openNamespaces = 0
+	final.flatPrefix = ""
+	final.flatDepth = 0
+	final.redactPath = ""
 
 	if final.TimeKey != "" {
 		final.AddTime(final.TimeKey, ent.Time)
 	}
-	if final.LevelKey != "" {
-		final.addKey(final.LevelKey)
-		cur := final.buf.Len()
-		final.EncodeLevel(ent.Level, final)
-		if cur == final.buf.Len() {
-			// User-supplied EncodeLevel was a no-op. Fall back to strings to keep
-			// output JSON valid.
-			final.AppendString(ent.Level.String())
-		}
-	}
-	if ent.LoggerName != "" && final.NameKey != "" {
-		final.addKey(final.NameKey)
+	final.addLevel(ent.Level)
+	if ent.LoggerName != "" && final.NameKey != "" && final.addKey(final.NameKey) {
 		final.AppendString(ent.LoggerName)
 	}
-	if ent.Caller.Defined && final.CallerKey != "" {
-		final.addKey(final.CallerKey)
-		cur := final.buf.Len()
-		final.EncodeCaller(ent.Caller, final)
-		if cur == final.buf.Len() {
-			// User-supplied EncodeCaller was a no-op. Fall back to strings to
-			// keep output JSON valid.
-			final.AppendString(ent.Caller.String())
-		}
+	if ent.Caller.Defined {
+		final.addCaller(ent.Caller)
 	}
-	if final.MessageKey != "" {
-		final.addKey(enc.MessageKey)
+	if final.MessageKey != "" && final.addKey(enc.MessageKey) {
 		final.AppendString(ent.Message)
 	}
+	final.finishValue()
 	if enc.buf.Len() > 0 {
-		final.addElementSeparator()
-		final.buf.Write(enc.buf.Bytes())
+		final.addContext(enc)
 	}
-	addFields(final, fields)
+	final.openNamespaces = enc.openNamespaces
+	final.flatPrefix = enc.flatPrefix
+	final.flatDepth = enc.flatDepth
+	final.redactPath = enc.redactPath
+	addFields(final, final.opts.orderFields(fields))
 	final.closeOpenNamespaces()
 	if ent.Stack != "" && final.StacktraceKey != "" {
-		final.AddString(final.StacktraceKey, ent.Stack)
+		final.addStack(final.StacktraceKey, ent.Stack)
 	}
+	final.finishValue()
+	final.addPseudonymKeyID()
+	final.addOverflowLabel()
 	final.buf.AppendByte('\n')
 
 	ret := final.buf
@@ -358,26 +543,97 @@ func (enc *ltsvEncoder) closeOpenNamespaces() {
 	for i := 0; i < enc.openNamespaces; i++ {
 		enc.buf.AppendByte('}')
 	}
+	enc.openNamespaces = 0
+	enc.flatPrefix = ""
+	enc.flatDepth = 0
+	enc.redactPath = ""
 }
 
-func (enc *ltsvEncoder) addKey(key string) {
-	enc.addElementSeparator()
+// flattening reports whether a nested value added now should be written as
+// separate labels rather than as JSON.
+func (enc *ltsvEncoder) flattening() bool {
+	if !enc.opts.flatten || enc.nestedLevel > 0 || enc.openNamespaces > 0 {
+		return false
+	}
+	return enc.opts.flattenDepth <= 0 || enc.flatDepth < enc.opts.flattenDepth
+}
+
+// flatState is the flattening state saved by pushFlatKey.
+type flatState struct {
+	prefix         string
+	depth          int
+	openNamespaces int
+	redactPath     string
+}
+
+// pushFlatKey appends key to the label prefix and returns the previous
+// state to be restored with popFlatKey.
+func (enc *ltsvEncoder) pushFlatKey(key string) flatState {
+	saved := flatState{
+		prefix:         enc.flatPrefix,
+		depth:          enc.flatDepth,
+		openNamespaces: enc.openNamespaces,
+		redactPath:     enc.pushRedactPath(key),
+	}
+	enc.flatPrefix = saved.prefix + key + enc.opts.flattenSep
+	enc.flatDepth++
+	return saved
+}
+
+// popFlatKey restores the state saved by pushFlatKey. Namespaces opened
+// while marshaling the nested value end with it.
+func (enc *ltsvEncoder) popFlatKey(saved flatState) {
+	for ; enc.openNamespaces > saved.openNamespaces; enc.openNamespaces-- {
+		enc.buf.AppendByte('}')
+	}
+	enc.flatPrefix = saved.prefix
+	enc.flatDepth = saved.depth
+	enc.redactPath = saved.redactPath
+}
+
+// addKey writes key and the separator before it, and reports whether the
+// value must follow. If the key is redacted, it writes the mask as the
+// value and returns false.
+func (enc *ltsvEncoder) addKey(key string) bool {
+	if enc.redactsKey(key) {
+		if enc.writeKey(key) {
+			enc.appendString(enc.opts.redaction.mask)
+		}
+		return false
+	}
+	return enc.writeKey(key)
+}
+
+// writeKey writes key and the separator before it. At the top level, it
+// returns false if the field must be dropped because of the key policy.
+func (enc *ltsvEncoder) writeKey(key string) bool {
 	if enc.nestedLevel == 0 && enc.openNamespaces == 0 {
-		if strings.ContainsRune(key, ':') {
-			panic("LTSV keys must not contain colon ':'")
+		enc.finishValue()
+		enc.lastKey = key
+		keyStart := enc.buf.Len()
+		if !enc.validLabel(key) {
+			if !enc.addInvalidKey(enc.flatPrefix + key) {
+				return false
+			}
+		} else {
+			enc.addElementSeparator()
+			enc.safeAddString(enc.flatPrefix)
+			enc.safeAddString(key)
+			enc.buf.AppendByte(':')
+			enc.justAfterKey = true
 		}
-		enc.safeAddString(key)
-		enc.buf.AppendByte(':')
-		enc.justAfterKey = true
+		enc.startValue(keyStart, key)
 	} else {
+		enc.addElementSeparator()
 		enc.buf.AppendByte('"')
 		enc.safeAddString(key)
 		enc.buf.AppendByte('"')
 		enc.buf.AppendByte(':')
-		if enc.spaced {
+		if enc.opts.spaced {
 			enc.buf.AppendByte(' ')
 		}
 	}
+	return true
 }
 
 func (enc *ltsvEncoder) addElementSeparator() {
@@ -397,7 +653,7 @@ func (enc *ltsvEncoder) addElementSeparator() {
 			return
 		default:
 			enc.buf.AppendByte(',')
-			if enc.spaced {
+			if enc.opts.spaced {
 				enc.buf.AppendByte(' ')
 			}
 		}
@@ -405,23 +661,19 @@ func (enc *ltsvEncoder) addElementSeparator() {
 }
 
 func (enc *ltsvEncoder) appendFloat(val float64, bitSize int) {
-	enc.addElementSeparator()
-	switch {
-	case math.IsNaN(val):
-		enc.buf.AppendString(`"NaN"`)
-	case math.IsInf(val, 1):
-		enc.buf.AppendString(`"+Inf"`)
-	case math.IsInf(val, -1):
-		enc.buf.AppendString(`"-Inf"`)
-	default:
-		enc.buf.AppendFloat(val, bitSize)
-	}
+	enc.appendFloatFormat(val, bitSize, enc.opts.floatFormat)
 }
 
 // safeAddString JSON-escapes a string and appends it to the internal buffer.
 // Unlike the standard library's encoder, it doesn't attempt to protect the
 // user from browser vulnerabilities or JSONP-related problems.
+// Top-level labels and values are escaped with the configured escape mode
+// instead.
 func (enc *ltsvEncoder) safeAddString(s string) {
+	if enc.opts.escapeMode != JSONEscape && enc.nestedLevel == 0 && enc.openNamespaces == 0 {
+		enc.addEscapedString(s)
+		return
+	}
 	for i := 0; i < len(s); {
 		if enc.tryAddRuneSelf(s[i]) {
 			i++
@@ -439,6 +691,10 @@ func (enc *ltsvEncoder) safeAddString(s string) {
 
 // safeAddByteString is no-alloc equivalent of safeAddString(string(s)) for s []byte.
 func (enc *ltsvEncoder) safeAddByteString(s []byte) {
+	if enc.opts.escapeMode != JSONEscape && enc.nestedLevel == 0 && enc.openNamespaces == 0 {
+		enc.addEscapedByteString(s)
+		return
+	}
 	for i := 0; i < len(s); {
 		if enc.tryAddRuneSelf(s[i]) {
 			i++
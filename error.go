@@ -0,0 +1,143 @@
+package ltsv
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// An ErrorFormat selects the labels written for error fields by the
+// StructuredErrors option. With an error field under the key "error", the
+// labels are:
+//
+//	error         the message of the error, as without the option
+//	error.type    the Go type of the error, e.g. "*fs.PathError"
+//	error.causes.0, error.causes.1, ...
+//	              the messages of the errors it wraps, found with Unwrap
+//	              methods depth-first, including those of errors.Join
+//	errorVerbose  the error formatted with %+v, written in the stacktrace
+//	              mode and filtered like stacktraces
+type ErrorFormat struct {
+	// Type writes the Go type of the error.
+	Type bool
+	// Causes writes the messages of wrapped errors.
+	Causes bool
+	// MaxCauses limits the number of causes. Zero or less means no limit.
+	MaxCauses int
+	// Verbose writes the verbose form of errors implementing fmt.Formatter
+	// if it differs from the message, as zap does. Otherwise it is
+	// omitted.
+	Verbose bool
+}
+
+// StructuredErrors makes the encoder write error fields, such as those
+// created with zap.Error, in the format f instead of as zap does.
+//
+// Zap writes error fields added with With or inside objects itself, so
+// the encoder only sees their message and verbose form. For those, only
+// the verbose form follows f, and no type or causes are written.
+func StructuredErrors(f ErrorFormat) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.errorFormat = &f
+	})
+}
+
+// addError writes err under key in the error format.
+func (enc *ltsvEncoder) addError(key string, err error) {
+	f := enc.opts.errorFormat
+	defer func() {
+		if rerr := recover(); rerr != nil {
+			// Like zap, write "<nil>" for a nil pointer whose Error method
+			// panics.
+			if v := reflect.ValueOf(err); v.Kind() == reflect.Ptr && v.IsNil() {
+				enc.AddString(key, "<nil>")
+				return
+			}
+			enc.AddString(key+"Error", fmt.Sprintf("PANIC=%v", rerr))
+		}
+	}()
+
+	basic := err.Error()
+	enc.AddString(key, basic)
+	// The verbose form below is not one written by zap.
+	enc.lastKey = ""
+	if f.Type {
+		enc.AddString(key+".type", fmt.Sprintf("%T", err))
+	}
+	if f.Causes {
+		for i, cause := range errorCauses(err, f.MaxCauses) {
+			enc.AddString(key+".causes."+strconv.Itoa(i), cause.Error())
+		}
+	}
+	if f.Verbose {
+		if e, ok := err.(fmt.Formatter); ok {
+			if verbose := fmt.Sprintf("%+v", e); verbose != basic {
+				enc.addStack(key+"Verbose", verbose)
+			}
+		}
+	}
+}
+
+// addErrorVerbose writes val, the verbose form of an error field written
+// by zap under key, in the error format, and reports whether key is such a
+// label. Zap writes it right after the message of the field, so it is
+// recognized as a top-level label whose key is that of the previous label
+// followed by "Verbose".
+func (enc *ltsvEncoder) addErrorVerbose(key, val string) bool {
+	f := enc.opts.errorFormat
+	if f == nil || enc.nestedLevel > 0 || enc.openNamespaces > 0 ||
+		enc.lastKey == "" || key != enc.lastKey+"Verbose" {
+		return false
+	}
+	if f.Verbose {
+		enc.lastKey = key
+		enc.addStack(key, val)
+	}
+	return true
+}
+
+// errorCauses returns up to max errors wrapped by err, depth-first. A max
+// of zero or less means no limit.
+func errorCauses(err error, max int) []error {
+	var causes []error
+	var walk func(error)
+	walk = func(err error) {
+		var wrapped []error
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			if w := e.Unwrap(); w != nil {
+				wrapped = []error{w}
+			}
+		case interface{ Unwrap() []error }:
+			wrapped = e.Unwrap()
+		case interface{ Errors() []error }:
+			wrapped = e.Errors()
+		}
+		for _, w := range wrapped {
+			if max > 0 && len(causes) >= max {
+				return
+			}
+			if w == nil {
+				continue
+			}
+			causes = append(causes, w)
+			walk(w)
+		}
+	}
+	walk(err)
+	return causes
+}
+
+// addField adds f to the encoder, writing error fields in the error
+// format if one is set.
+func (enc *ltsvEncoder) addField(f zapcore.Field) {
+	if f.Type == zapcore.ErrorType && enc.opts.errorFormat != nil {
+		if err, ok := f.Interface.(error); ok {
+			enc.addError(f.Key, err)
+			return
+		}
+	}
+	f.AddTo(enc)
+}
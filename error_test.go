@@ -0,0 +1,134 @@
+package ltsv_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type stackError struct{ msg string }
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\nmain.handle\n\t/app/main.go:12\nruntime.main\n\t/go/src/runtime/proc.go:250", e.msg)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+func TestStructuredErrors(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	root := errors.New("connection refused")
+	wrapped := fmt.Errorf("dial: %w", root)
+	joined := errors.Join(wrapped, errors.New("timeout"))
+	testCases := []struct {
+		opts  []ltsv.Option
+		field zapcore.Field
+		want  string
+	}{
+		{
+			field: zap.Error(wrapped),
+			want:  "level:info\tmsg:hello\terror:dial: connection refused\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{Type: true, Causes: true})},
+			field: zap.Error(wrapped),
+			want:  "level:info\tmsg:hello\terror:dial: connection refused\terror.type:*fmt.wrapError\terror.causes.0:connection refused\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{Causes: true})},
+			field: zap.NamedError("err", joined),
+			want:  "level:info\tmsg:hello\terr:dial: connection refused\\ntimeout\terr.causes.0:dial: connection refused\terr.causes.1:connection refused\terr.causes.2:timeout\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{Causes: true, MaxCauses: 1})},
+			field: zap.Error(joined),
+			want:  "level:info\tmsg:hello\terror:dial: connection refused\\ntimeout\terror.causes.0:dial: connection refused\n",
+		},
+		{
+			field: zap.Error(&stackError{"boom"}),
+			want:  "level:info\tmsg:hello\terror:boom\terrorVerbose:boom\\nmain.handle\\n\\t/app/main.go:12\\nruntime.main\\n\\t/go/src/runtime/proc.go:250\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{})},
+			field: zap.Error(&stackError{"boom"}),
+			want:  "level:info\tmsg:hello\terror:boom\n",
+		},
+		{
+			opts: []ltsv.Option{
+				ltsv.StructuredErrors(ltsv.ErrorFormat{Verbose: true}),
+				ltsv.Stacktrace(ltsv.StacktraceJoined),
+				ltsv.StacktraceFilter("runtime."),
+			},
+			field: zap.Error(&stackError{"boom"}),
+			want:  "level:info\tmsg:hello\terror:boom\terrorVerbose:main.handle /app/main.go:12\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{Type: true})},
+			field: zap.Error((*stackError)(nil)),
+			want:  "level:info\tmsg:hello\terror:<nil>\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{tc.field})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
+
+func TestStructuredErrorsWith(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts []ltsv.Option
+		with []zapcore.Field
+		want string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{Type: true})},
+			with: []zapcore.Field{zap.Error(&stackError{"boom"}), zap.String("userVerbose", "x")},
+			want: "level:info\tmsg:hello\terror:boom\tuserVerbose:x\n",
+		},
+		{
+			opts: []ltsv.Option{
+				ltsv.StructuredErrors(ltsv.ErrorFormat{Verbose: true}),
+				ltsv.Stacktrace(ltsv.StacktraceJoined),
+				ltsv.StacktraceFilter("runtime."),
+			},
+			with: []zapcore.Field{zap.NamedError("err", &stackError{"boom"})},
+			want: "level:info\tmsg:hello\terr:boom\terrVerbose:main.handle /app/main.go:12\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.StructuredErrors(ltsv.ErrorFormat{Verbose: true}), ltsv.Stacktrace(ltsv.StacktraceLabels)},
+			with: []zapcore.Field{zap.Error(&stackError{"boom"})},
+			want: "level:info\tmsg:hello\terror:boom\terrorVerbose.0:main.handle /app/main.go:12\terrorVerbose.1:runtime.main /go/src/runtime/proc.go:250\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...).Clone()
+		for _, f := range tc.with {
+			f.AddTo(enc)
+		}
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, nil)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
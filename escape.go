@@ -0,0 +1,297 @@
+package ltsv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// An EscapeMode selects how the encoder escapes top-level labels and
+// values. Nested values are always written as JSON and escaped as such.
+type EscapeMode int
+
+const (
+	// JSONEscape escapes strings in the same way as the content of JSON
+	// strings. This is the default mode.
+	JSONEscape EscapeMode = iota
+	// MinimalEscape only escapes the characters LTSV cannot hold, that is
+	// tab, CR and LF as \t, \r and \n. Other characters including
+	// backslashes are written as is, so a value which already contains
+	// one of these escape sequences cannot be told apart when unescaped.
+	MinimalEscape
+	// PercentEscape percent-encodes control characters, DEL, the percent
+	// sign and bytes which are not valid UTF-8, e.g. a tab becomes %09.
+	PercentEscape
+)
+
+// ValueEscaping sets the escape mode for top-level labels and values,
+// including the stacktrace.
+func ValueEscaping(mode EscapeMode) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.escapeMode = mode
+	})
+}
+
+// String returns a lower-case ASCII representation of the escape mode.
+func (m EscapeMode) String() string {
+	switch m {
+	case JSONEscape:
+		return "json"
+	case MinimalEscape:
+		return "minimal"
+	case PercentEscape:
+		return "percent"
+	default:
+		return fmt.Sprintf("EscapeMode(%d)", int(m))
+	}
+}
+
+// MarshalText marshals the escape mode to text. See String.
+func (m EscapeMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText unmarshals text to an escape mode. Valid values are
+// "json", "minimal" and "percent".
+func (m *EscapeMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "json", "":
+		*m = JSONEscape
+	case "minimal":
+		*m = MinimalEscape
+	case "percent":
+		*m = PercentEscape
+	default:
+		return fmt.Errorf("ltsv: unknown escape mode %q", text)
+	}
+	return nil
+}
+
+// Unescape reverses the escaping of the mode for a label or value.
+func (m EscapeMode) Unescape(s string) (string, error) {
+	switch m {
+	case JSONEscape:
+		return unescapeJSON(s)
+	case MinimalEscape:
+		return unescapeMinimal(s), nil
+	case PercentEscape:
+		return unescapePercent(s)
+	default:
+		return "", fmt.Errorf("ltsv: unknown escape mode %d", int(m))
+	}
+}
+
+// addEscapedString appends s to the buffer using the escape mode, which
+// must not be JSONEscape.
+func (enc *ltsvEncoder) addEscapedString(s string) {
+	switch enc.opts.escapeMode {
+	case MinimalEscape:
+		for i := 0; i < len(s); i++ {
+			enc.addMinimalEscapedByte(s[i])
+		}
+	default:
+		for i := 0; i < len(s); {
+			if s[i] < utf8.RuneSelf {
+				enc.addPercentEscapedByte(s[i])
+				i++
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				enc.addPercentEncoded(s[i])
+			} else {
+				enc.buf.AppendString(s[i : i+size])
+			}
+			i += size
+		}
+	}
+}
+
+// addEscapedByteString is no-alloc equivalent of addEscapedString(string(s)) for s []byte.
+func (enc *ltsvEncoder) addEscapedByteString(s []byte) {
+	switch enc.opts.escapeMode {
+	case MinimalEscape:
+		for i := 0; i < len(s); i++ {
+			enc.addMinimalEscapedByte(s[i])
+		}
+	default:
+		for i := 0; i < len(s); {
+			if s[i] < utf8.RuneSelf {
+				enc.addPercentEscapedByte(s[i])
+				i++
+				continue
+			}
+			r, size := utf8.DecodeRune(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				enc.addPercentEncoded(s[i])
+			} else {
+				enc.buf.Write(s[i : i+size])
+			}
+			i += size
+		}
+	}
+}
+
+func (enc *ltsvEncoder) addMinimalEscapedByte(b byte) {
+	switch b {
+	case '\t':
+		enc.buf.AppendString(`\t`)
+	case '\n':
+		enc.buf.AppendString(`\n`)
+	case '\r':
+		enc.buf.AppendString(`\r`)
+	default:
+		enc.buf.AppendByte(b)
+	}
+}
+
+// addPercentEscapedByte appends an ASCII byte b, percent-encoding it if needed.
+func (enc *ltsvEncoder) addPercentEscapedByte(b byte) {
+	if b < 0x20 || b == 0x7f || b == '%' {
+		enc.addPercentEncoded(b)
+	} else {
+		enc.buf.AppendByte(b)
+	}
+}
+
+func (enc *ltsvEncoder) addPercentEncoded(b byte) {
+	enc.buf.AppendByte('%')
+	enc.buf.AppendByte(upperhex[b>>4])
+	enc.buf.AppendByte(upperhex[b&0xF])
+}
+
+func unescapeMinimal(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 't':
+				b.WriteByte('\t')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case 'r':
+				b.WriteByte('\r')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+var errInvalidPercentEscape = errors.New("ltsv: invalid percent escape")
+
+func unescapePercent(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", errInvalidPercentEscape
+		}
+		hi, ok1 := unhex(s[i+1])
+		lo, ok2 := unhex(s[i+2])
+		if !ok1 || !ok2 {
+			return "", errInvalidPercentEscape
+		}
+		b.WriteByte(hi<<4 | lo)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+var errInvalidJSONEscape = errors.New("ltsv: invalid JSON escape")
+
+func unescapeJSON(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", errInvalidJSONEscape
+		}
+		switch s[i] {
+		case '"', '\\', '/':
+			b.WriteByte(s[i])
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, ok := parseHex4(s[i+1:])
+			if !ok {
+				return "", errInvalidJSONEscape
+			}
+			i += 4
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], `\u`) {
+				if r2, ok := parseHex4(s[i+3:]); ok {
+					if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+						r = dec
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			return "", errInvalidJSONEscape
+		}
+	}
+	return b.String(), nil
+}
+
+func parseHex4(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	var r rune
+	for i := 0; i < 4; i++ {
+		v, ok := unhex(s[i])
+		if !ok {
+			return 0, false
+		}
+		r = r<<4 | rune(v)
+	}
+	return r, true
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
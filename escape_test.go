@@ -0,0 +1,114 @@
+package ltsv_test
+
+import (
+	"strings"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestValueEscaping(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.LevelKey = ""
+
+	testCases := []struct {
+		mode ltsv.EscapeMode
+		want string
+	}{
+		{
+			mode: ltsv.JSONEscape,
+			want: "msg:hello\ts:a\\tb \\\"c\\\\d\\\" 100%\\u0001\tb:x\\ny\\ufffd\ta\\tb:1\tarr:[\"a\\tb\"]\tstacktrace:main.f\\n\\tmain.go:1\n",
+		},
+		{
+			mode: ltsv.MinimalEscape,
+			want: "msg:hello\ts:a\\tb \"c\\d\" 100%\x01\tb:x\\ny\xff\ta\\tb:1\tarr:[\"a\\tb\"]\tstacktrace:main.f\\n\\tmain.go:1\n",
+		},
+		{
+			mode: ltsv.PercentEscape,
+			want: "msg:hello\ts:a%09b \"c\\d\" 100%25%01\tb:x%0Ay%FF\ta%09b:1\tarr:[\"a\\tb\"]\tstacktrace:main.f%0A%09main.go:1\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, ltsv.ValueEscaping(tc.mode))
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello", Stack: "main.f\n\tmain.go:1"}, []zapcore.Field{
+			zap.String("s", "a\tb \"c\\d\" 100%\x01"),
+			zap.ByteString("b", []byte("x\ny\xff")),
+			zap.Int("a\tb", 1),
+			zap.Strings("arr", []string{"a\tb"}),
+		})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("mode=%s, got=%q, want=%q", tc.mode, got, tc.want)
+		}
+	}
+}
+
+func TestEscapeModeUnescape(t *testing.T) {
+	cfg := zapcore.EncoderConfig{MessageKey: "msg"}
+	values := []string{
+		"",
+		"plain",
+		"a\tb\r\nc",
+		`quote " backslash \ percent %41`,
+		"\x00\x1f\x7f",
+		"日本語 \U0001F600",
+	}
+	for _, mode := range []ltsv.EscapeMode{ltsv.JSONEscape, ltsv.MinimalEscape, ltsv.PercentEscape} {
+		enc := ltsv.NewLTSVEncoder(cfg, ltsv.ValueEscaping(mode))
+		for _, v := range values {
+			if mode == ltsv.MinimalEscape && strings.Contains(v, `\`) {
+				// Not reversible by design.
+				continue
+			}
+			buf, err := enc.EncodeEntry(zapcore.Entry{Message: v}, nil)
+			if err != nil {
+				t.Fatalf("failed to encode entry; err=%+v", err)
+			}
+			escaped := strings.TrimSuffix(strings.TrimPrefix(buf.String(), "msg:"), "\n")
+			got, err := mode.Unescape(escaped)
+			if err != nil {
+				t.Errorf("mode=%s, unescape %q: %v", mode, escaped, err)
+			} else if got != v {
+				t.Errorf("mode=%s, got=%q, want=%q", mode, got, v)
+			}
+		}
+	}
+}
+
+func TestEscapeModeUnescapeErrors(t *testing.T) {
+	testCases := []struct {
+		mode ltsv.EscapeMode
+		in   string
+	}{
+		{ltsv.JSONEscape, `a\`},
+		{ltsv.JSONEscape, `\x41`},
+		{ltsv.JSONEscape, `\u12`},
+		{ltsv.PercentEscape, `%4`},
+		{ltsv.PercentEscape, `%zz`},
+	}
+	for _, tc := range testCases {
+		if _, err := tc.mode.Unescape(tc.in); err == nil {
+			t.Errorf("mode=%s, in=%q, want error", tc.mode, tc.in)
+		}
+	}
+}
+
+func TestEscapeModeUnmarshalText(t *testing.T) {
+	for _, mode := range []ltsv.EscapeMode{ltsv.JSONEscape, ltsv.MinimalEscape, ltsv.PercentEscape} {
+		var got ltsv.EscapeMode
+		if err := got.UnmarshalText([]byte(mode.String())); err != nil {
+			t.Errorf("mode=%s, unexpected error: %v", mode, err)
+		} else if got != mode {
+			t.Errorf("got=%s, want=%s", got, mode)
+		}
+	}
+	var m ltsv.EscapeMode
+	if err := m.UnmarshalText([]byte("base64")); err == nil {
+		t.Error("want error for unknown mode")
+	}
+}
@@ -23,8 +23,8 @@ package ltsv
 
 import "go.uber.org/zap/zapcore"
 
-func addFields(enc zapcore.ObjectEncoder, fields []zapcore.Field) {
+func addFields(enc *ltsvEncoder, fields []zapcore.Field) {
 	for i := range fields {
-		fields[i].AddTo(enc)
+		enc.addField(fields[i])
 	}
 }
@@ -0,0 +1,54 @@
+package ltsv
+
+import (
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// flatArrayEncoder writes each array element as a top-level label whose
+// name is the element index appended to the current label prefix.
+type flatArrayEncoder struct {
+	enc *ltsvEncoder
+	i   int
+}
+
+func (a *flatArrayEncoder) nextKey() string {
+	key := strconv.Itoa(a.i)
+	a.i++
+	return key
+}
+
+func (a *flatArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	return a.enc.AddArray(a.nextKey(), arr)
+}
+
+func (a *flatArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return a.enc.AddObject(a.nextKey(), obj)
+}
+
+func (a *flatArrayEncoder) AppendReflected(val interface{}) error {
+	return a.enc.AddReflected(a.nextKey(), val)
+}
+
+func (a *flatArrayEncoder) AppendBool(v bool)              { a.enc.AddBool(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendByteString(v []byte)      { a.enc.AddByteString(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendComplex128(v complex128)  { a.enc.AddComplex128(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendComplex64(v complex64)    { a.enc.AddComplex64(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendDuration(v time.Duration) { a.enc.AddDuration(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendFloat64(v float64)        { a.enc.AddFloat64(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendFloat32(v float32)        { a.enc.AddFloat32(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendInt(v int)                { a.enc.AddInt(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendInt64(v int64)            { a.enc.AddInt64(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendInt32(v int32)            { a.enc.AddInt32(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendInt16(v int16)            { a.enc.AddInt16(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendInt8(v int8)              { a.enc.AddInt8(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendString(v string)          { a.enc.AddString(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendTime(v time.Time)         { a.enc.AddTime(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendUint(v uint)              { a.enc.AddUint(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendUint64(v uint64)          { a.enc.AddUint64(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendUint32(v uint32)          { a.enc.AddUint32(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendUint16(v uint16)          { a.enc.AddUint16(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendUint8(v uint8)            { a.enc.AddUint8(a.nextKey(), v) }
+func (a *flatArrayEncoder) AppendUintptr(v uintptr)        { a.enc.AddUintptr(a.nextKey(), v) }
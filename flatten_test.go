@@ -0,0 +1,122 @@
+package ltsv_test
+
+import (
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFlatten(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts   []ltsv.Option
+		ent    zapcore.Entry
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.Flatten(".", 0)},
+			fields: []zapcore.Field{
+				zap.Int("a", 1),
+				zap.Namespace("http"),
+				zap.String("method", "GET"),
+				zap.Int("status", 200),
+			},
+			want: "level:info\tmsg:hello\ta:1\thttp.method:GET\thttp.status:200\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Flatten(".", 0)},
+			fields: []zapcore.Field{
+				zap.Array("users", users{jane}),
+				zap.Int("n", 1),
+			},
+			want: "level:info\tmsg:hello\tusers.0.name:Jane Doe\tusers.0.email:jane@test.com\tusers.0.created_at:315576000000000000\tn:1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Flatten("_", 0)},
+			fields: []zapcore.Field{
+				zap.Dict("req", zap.String("path", "/"), zap.Dict("header", zap.String("host", "example.com"))),
+				zap.Ints("codes", []int{1, 2}),
+			},
+			want: "level:info\tmsg:hello\treq_path:/\treq_header_host:example.com\tcodes_0:1\tcodes_1:2\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Flatten(".", 1)},
+			fields: []zapcore.Field{
+				zap.Dict("req", zap.String("path", "/"), zap.Dict("header", zap.String("host", "example.com"))),
+				zap.Namespace("ns1"),
+				zap.Namespace("ns2"),
+				zap.Int("a", 1),
+			},
+			want: "level:info\tmsg:hello\treq.path:/\treq.header:{\"host\":\"example.com\"}\tns1.ns2:{\"a\":1}\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Flatten(".", 0)},
+			ent: zapcore.Entry{
+				Stack: "stack",
+			},
+			fields: []zapcore.Field{
+				zap.Namespace("ns"),
+				zap.Int("a", 1),
+			},
+			want: "level:info\tmsg:hello\tns.a:1\tstacktrace:stack\n",
+		},
+		{
+			fields: []zapcore.Field{
+				zap.Namespace("ns"),
+				zap.Int("a", 1),
+			},
+			ent: zapcore.Entry{
+				Stack: "stack",
+			},
+			want: "level:info\tmsg:hello\tns:{\"a\":1}\tstacktrace:stack\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		ent := tc.ent
+		ent.Message = "hello"
+		buf, err := enc.EncodeEntry(ent, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestFlattenWith(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts []ltsv.Option
+		want string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.Flatten(".", 0)},
+			want: "level:info\tmsg:hello\tid:1\thttp.method:GET\thttp.status:200\n",
+		},
+		{
+			want: "level:info\tmsg:hello\tid:1\thttp:{\"method\":\"GET\",\"status\":200}\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...).Clone()
+		enc.AddInt("id", 1)
+		enc.OpenNamespace("http")
+		enc.AddString("method", "GET")
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{zap.Int("status", 200)})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ltsv
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// A FloatFormat selects how the encoder formats floating-point numbers.
+// Fmt and Prec have the same meaning as the arguments of
+// strconv.FormatFloat, where Fmt is one of 'f', 'e', 'E', 'g' and 'G' and a
+// Prec of -1 means the smallest number of digits necessary to represent
+// the value exactly.
+//
+// The zero FloatFormat is the default format. It writes numbers like 2.39
+// or 1000000 without an exponent, like FloatFormat{Fmt: 'f', Prec: -1}.
+type FloatFormat struct {
+	Fmt  byte
+	Prec int
+}
+
+// String returns the format as its Fmt character followed by the
+// precision unless it is -1, e.g. "g" or "f3". It returns "" for the
+// default format.
+func (f FloatFormat) String() string {
+	if f.isDefault() {
+		return ""
+	}
+	if f.Prec < 0 {
+		return string(f.Fmt)
+	}
+	return string(f.Fmt) + strconv.Itoa(f.Prec)
+}
+
+// MarshalText marshals the format to text. See String.
+func (f FloatFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText unmarshals text to a format. Valid values are one of the
+// characters "f", "e", "E", "g" and "G", optionally followed by a
+// precision, e.g. "f3", or "" for the default format.
+func (f *FloatFormat) UnmarshalText(text []byte) error {
+	s := string(text)
+	if s == "" {
+		*f = FloatFormat{}
+		return nil
+	}
+	parsed := FloatFormat{Fmt: s[0], Prec: -1}
+	if len(s) > 1 {
+		prec, err := strconv.Atoi(s[1:])
+		if err != nil || prec < 0 {
+			return fmt.Errorf("ltsv: invalid float format %q", text)
+		}
+		parsed.Prec = prec
+	}
+	if !parsed.valid() {
+		return fmt.Errorf("ltsv: invalid float format %q", text)
+	}
+	*f = parsed
+	return nil
+}
+
+// isDefault reports whether f is the zero FloatFormat.
+func (f FloatFormat) isDefault() bool {
+	return f == FloatFormat{}
+}
+
+// valid reports whether f is the default format or a format accepted by
+// strconv.FormatFloat.
+func (f FloatFormat) valid() bool {
+	if f.isDefault() {
+		return true
+	}
+	switch f.Fmt {
+	case 'f', 'e', 'E', 'g', 'G':
+		return f.Prec >= -1
+	default:
+		return false
+	}
+}
+
+// A NonFiniteFloats decides how the encoder writes NaN and infinite
+// floating-point numbers inside JSON values. At the top level, they are
+// always written as NaN, +Inf and -Inf without quotes.
+type NonFiniteFloats int
+
+const (
+	// NonFiniteString writes the strings "NaN", "+Inf" and "-Inf". This is
+	// the default.
+	NonFiniteString NonFiniteFloats = iota
+	// NonFiniteNull writes null.
+	NonFiniteNull
+)
+
+// String returns a lower-case ASCII representation of the policy.
+func (n NonFiniteFloats) String() string {
+	switch n {
+	case NonFiniteString:
+		return "string"
+	case NonFiniteNull:
+		return "null"
+	default:
+		return fmt.Sprintf("NonFiniteFloats(%d)", int(n))
+	}
+}
+
+// MarshalText marshals the policy to text. See String.
+func (n NonFiniteFloats) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText unmarshals text to a policy. Valid values are "string"
+// and "null".
+func (n *NonFiniteFloats) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "string", "":
+		*n = NonFiniteString
+	case "null":
+		*n = NonFiniteNull
+	default:
+		return fmt.Errorf("ltsv: unknown non-finite float policy %q", text)
+	}
+	return nil
+}
+
+// FloatFormatting sets the format of floating-point numbers, including
+// the parts of complex numbers. The zero FloatFormat restores the default
+// format, and an invalid format is ignored.
+func FloatFormatting(f FloatFormat) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		if f.valid() {
+			opts.floatFormat = f
+		}
+	})
+}
+
+// FloatFormattingByKey overrides the format of FloatFormatting for
+// floating-point values with the given keys. Keys are matched against the
+// key of the field itself, and elements of arrays use the format of the
+// encoder. Invalid formats are ignored.
+func FloatFormattingByKey(formats map[string]FloatFormat) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.floatFormatByKey = make(map[string]FloatFormat, len(formats))
+		for k, v := range formats {
+			if v.valid() {
+				opts.floatFormatByKey[k] = v
+			}
+		}
+	})
+}
+
+// NonFiniteFloatsInJSON sets how NaN and infinite numbers are written
+// inside JSON values.
+func NonFiniteFloatsInJSON(n NonFiniteFloats) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.nonFinite = n
+	})
+}
+
+// floatFormatOf returns the format for a floating-point value with key.
+func (o *encoderOptions) floatFormatOf(key string) FloatFormat {
+	if f, ok := o.floatFormatByKey[key]; ok {
+		return f
+	}
+	return o.floatFormat
+}
+
+// appendFloatFormat appends val in the format f. Non-finite numbers are
+// quoted or replaced with null inside JSON values.
+func (enc *ltsvEncoder) appendFloatFormat(val float64, bitSize int, f FloatFormat) {
+	enc.addElementSeparator()
+	if enc.nestedLevel > 0 || enc.openNamespaces > 0 {
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			if enc.opts.nonFinite == NonFiniteNull {
+				enc.buf.AppendString("null")
+				return
+			}
+			enc.buf.AppendByte('"')
+			enc.addFloat(val, bitSize, f)
+			enc.buf.AppendByte('"')
+			return
+		}
+	}
+	enc.addFloat(val, bitSize, f)
+}
+
+// addFloat appends val in the format f. NaN and infinite numbers are
+// written as NaN, +Inf and -Inf.
+func (enc *ltsvEncoder) addFloat(val float64, bitSize int, f FloatFormat) {
+	if f.isDefault() {
+		enc.buf.AppendFloat(val, bitSize)
+		return
+	}
+	var scratch [32]byte
+	enc.buf.Write(strconv.AppendFloat(scratch[:0], val, f.Fmt, f.Prec, bitSize))
+}
@@ -0,0 +1,108 @@
+package ltsv_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFloatFormatting(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	nonFinite := []zapcore.Field{
+		zap.Float64("nan", math.NaN()),
+		zap.Float64("inf", math.Inf(1)),
+		zap.Float32("ninf", float32(math.Inf(-1))),
+	}
+	testCases := []struct {
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			fields: nonFinite,
+			want:   "level:info\tmsg:hello\tnan:NaN\tinf:+Inf\tninf:-Inf\n",
+		},
+		{
+			fields: []zapcore.Field{zap.Dict("m", nonFinite...), zap.Float64s("a", []float64{1, math.NaN()})},
+			want:   "level:info\tmsg:hello\tm:{\"nan\":\"NaN\",\"inf\":\"+Inf\",\"ninf\":\"-Inf\"}\ta:[1,\"NaN\"]\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.NonFiniteFloatsInJSON(ltsv.NonFiniteNull)},
+			fields: []zapcore.Field{zap.Dict("m", nonFinite...), zap.Float64("nan", math.NaN())},
+			want:   "level:info\tmsg:hello\tm:{\"nan\":null,\"inf\":null,\"ninf\":null}\tnan:NaN\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.NonFiniteFloatsInJSON(ltsv.NonFiniteNull), ltsv.Flatten(".", 0)},
+			fields: []zapcore.Field{zap.Dict("m", zap.Float64("nan", math.NaN()))},
+			want:   "level:info\tmsg:hello\tm.nan:NaN\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.FloatFormatting(ltsv.FloatFormat{Fmt: 'e', Prec: 2})},
+			fields: []zapcore.Field{zap.Float64("a", 1234.5678), zap.Complex128("c", 1+2i), zap.Float64("nan", math.NaN())},
+			want:   "level:info\tmsg:hello\ta:1.23e+03\tc:1.00e+00+2.00e+00i\tnan:NaN\n",
+		},
+		{
+			opts: []ltsv.Option{
+				ltsv.FloatFormatting(ltsv.FloatFormat{Fmt: 'g', Prec: -1}),
+				ltsv.FloatFormattingByKey(map[string]ltsv.FloatFormat{"reqtime": {Fmt: 'f', Prec: 3}}),
+			},
+			fields: []zapcore.Field{zap.Float64("reqtime", 0.1), zap.Float64("size", 1e21), zap.Dict("m", zap.Float64("reqtime", 2))},
+			want:   "level:info\tmsg:hello\treqtime:0.100\tsize:1e+21\tm:{\"reqtime\":2.000}\n",
+		},
+		{
+			opts: []ltsv.Option{
+				ltsv.FloatFormatting(ltsv.FloatFormat{Fmt: 'g', Prec: -1}),
+				ltsv.FloatFormattingByKey(map[string]ltsv.FloatFormat{"reqtime": {}}),
+			},
+			fields: []zapcore.Field{zap.Float64("reqtime", 1e21), zap.Float64("size", 1e21)},
+			want:   "level:info\tmsg:hello\treqtime:1000000000000000000000\tsize:1e+21\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestFloatFormatText(t *testing.T) {
+	testCases := []struct {
+		text string
+		want ltsv.FloatFormat
+	}{
+		{text: "f", want: ltsv.FloatFormat{Fmt: 'f', Prec: -1}},
+		{text: "g", want: ltsv.FloatFormat{Fmt: 'g', Prec: -1}},
+		{text: "e6", want: ltsv.FloatFormat{Fmt: 'e', Prec: 6}},
+		{text: "f0", want: ltsv.FloatFormat{Fmt: 'f', Prec: 0}},
+		{text: "", want: ltsv.FloatFormat{}},
+	}
+	for _, tc := range testCases {
+		var got ltsv.FloatFormat
+		if err := json.Unmarshal([]byte(`"`+tc.text+`"`), &got); err != nil {
+			t.Fatalf("failed to unmarshal %q; err=%v", tc.text, err)
+		}
+		if got != tc.want {
+			t.Errorf("got=%+v, want=%+v", got, tc.want)
+		}
+		if s := got.String(); s != tc.text {
+			t.Errorf("got=%q, want=%q", s, tc.text)
+		}
+	}
+	for _, text := range []string{"x", "f-1", "fx", "b"} {
+		var f ltsv.FloatFormat
+		if err := f.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("got no error for %q, want error", text)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package ltsv
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// KeyErrorKey is the label under which the original key is written when
+// FallbackInvalidKey is in effect.
+const KeyErrorKey = "ltsvKeyError"
+
+// DefaultFallbackKey is the label used by FallbackInvalidKey unless
+// another one is set with the FallbackKey option.
+const DefaultFallbackKey = "ltsvInvalidKey"
+
+// For percent-encoding; see ltsvEncoder.percentEscapeKey below.
+const upperhex = "0123456789ABCDEF"
+
+// A KeyPolicy decides what the encoder does with a key that is not a valid
+// LTSV label, such as one containing a colon ':' or, with StrictKeys, any
+// character outside of the ltsv.org label charset.
+type KeyPolicy int
+
+const (
+	// PanicOnInvalidKey panics. This is the default policy.
+	PanicOnInvalidKey KeyPolicy = iota
+	// ReplaceInvalidKey replaces each invalid character with an underscore '_'.
+	// An empty key is replaced with a single underscore.
+	ReplaceInvalidKey
+	// EscapeInvalidKey percent-encodes each invalid character as well as
+	// the percent sign itself, e.g. "host:port" becomes "host%3Aport".
+	// Note that the result is not a valid label with StrictKeys.
+	EscapeInvalidKey
+	// DropInvalidKey drops the field.
+	DropInvalidKey
+	// FallbackInvalidKey writes the original key under the KeyErrorKey
+	// label followed by the value under the fallback label.
+	FallbackInvalidKey
+)
+
+// String returns a lower-case ASCII representation of the key policy.
+func (p KeyPolicy) String() string {
+	switch p {
+	case PanicOnInvalidKey:
+		return "panic"
+	case ReplaceInvalidKey:
+		return "replace"
+	case EscapeInvalidKey:
+		return "escape"
+	case DropInvalidKey:
+		return "drop"
+	case FallbackInvalidKey:
+		return "fallback"
+	default:
+		return fmt.Sprintf("KeyPolicy(%d)", int(p))
+	}
+}
+
+// MarshalText marshals the key policy to text. See String.
+func (p KeyPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText unmarshals text to a key policy. Valid values are
+// "panic", "replace", "escape", "drop" and "fallback".
+func (p *KeyPolicy) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "panic", "":
+		*p = PanicOnInvalidKey
+	case "replace":
+		*p = ReplaceInvalidKey
+	case "escape":
+		*p = EscapeInvalidKey
+	case "drop":
+		*p = DropInvalidKey
+	case "fallback":
+		*p = FallbackInvalidKey
+	default:
+		return fmt.Errorf("ltsv: unknown key policy %q", text)
+	}
+	return nil
+}
+
+// InvalidKeyPolicy sets the policy for keys that are not valid LTSV labels.
+// The policy applies to field keys, keys added with With, and the keys
+// configured in zapcore.EncoderConfig alike.
+func InvalidKeyPolicy(policy KeyPolicy) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.keyPolicy = policy
+	})
+}
+
+// FallbackKey sets the label used by FallbackInvalidKey.
+func FallbackKey(key string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.fallbackKey = key
+	})
+}
+
+// StrictKeys makes the encoder accept only non-empty labels consisting of
+// the characters allowed by the ltsv.org specification, [0-9A-Za-z_.-].
+// Other labels are handled according to the key policy.
+func StrictKeys() Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.strictKeys = true
+	})
+}
+
+// KeyWarningOutput makes the encoder report each invalid key to ws before
+// applying the key policy, in lines like
+//
+//	2017-05-03T21:09:11.983Z ltsv: invalid key "host:port"
+//
+// with the time in UTC in RFC 3339 format. It is intended for development,
+// to find keys which would otherwise be silently replaced or dropped.
+//
+// The warnings do not depend on the Development setting of zap, since the
+// encoder does not know it, but Config.Build enables them with the error
+// output of the logger if Development is set.
+func KeyWarningOutput(ws zapcore.WriteSyncer) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.keyWarningOutput = zapcore.Lock(ws)
+	})
+}
+
+// keyWarningTimeLayout is the RFC 3339 layout of the times of key warnings.
+const keyWarningTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// validLabel reports whether key is a valid label at the current position.
+func (enc *ltsvEncoder) validLabel(key string) bool {
+	if enc.opts.strictKeys && enc.flatPrefix == "" && key == "" {
+		return false
+	}
+	return enc.validKey(enc.flatPrefix) && enc.validKey(key)
+}
+
+func (enc *ltsvEncoder) validKey(key string) bool {
+	if !enc.opts.strictKeys {
+		return !strings.ContainsRune(key, ':')
+	}
+	for i := 0; i < len(key); i++ {
+		if !validStrictKeyByte(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (enc *ltsvEncoder) validKeyRune(r rune) bool {
+	if !enc.opts.strictKeys {
+		return r != ':'
+	}
+	return r < utf8.RuneSelf && validStrictKeyByte(byte(r))
+}
+
+func validStrictKeyByte(b byte) bool {
+	return '0' <= b && b <= '9' || 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' ||
+		b == '_' || b == '.' || b == '-'
+}
+
+// addInvalidKey writes key according to the key policy. It returns false if
+// the field must be dropped.
+func (enc *ltsvEncoder) addInvalidKey(key string) bool {
+	if enc.opts.keyWarningOutput != nil {
+		fmt.Fprintf(enc.opts.keyWarningOutput, "%s ltsv: invalid key %q\n", time.Now().UTC().Format(keyWarningTimeLayout), key)
+	}
+	switch enc.opts.keyPolicy {
+	case ReplaceInvalidKey:
+		enc.addElementSeparator()
+		if key == "" {
+			key = "_"
+		}
+		enc.safeAddString(strings.Map(func(r rune) rune {
+			if enc.validKeyRune(r) {
+				return r
+			}
+			return '_'
+		}, key))
+	case EscapeInvalidKey:
+		enc.addElementSeparator()
+		if key == "" {
+			key = "_"
+		}
+		if enc.opts.escapeMode == PercentEscape {
+			// Avoid escaping the percent signs again.
+			enc.buf.AppendString(enc.percentEscapeKey(key))
+		} else {
+			enc.safeAddString(enc.percentEscapeKey(key))
+		}
+	case DropInvalidKey:
+		return false
+	case FallbackInvalidKey:
+		enc.addElementSeparator()
+		enc.buf.AppendString(KeyErrorKey)
+		enc.buf.AppendByte(':')
+		enc.safeAddString(key)
+		enc.buf.AppendByte('\t')
+		enc.safeAddString(enc.opts.fallbackKey)
+	default:
+		if enc.opts.strictKeys {
+			panic(fmt.Sprintf("LTSV labels must be non-empty and consist of [0-9A-Za-z_.-]: %q", key))
+		}
+		panic("LTSV keys must not contain colon ':'")
+	}
+	enc.buf.AppendByte(':')
+	enc.justAfterKey = true
+	return true
+}
+
+// percentEscapeKey percent-encodes the bytes of invalid characters in key
+// as well as those which PercentEscape would encode.
+func (enc *ltsvEncoder) percentEscapeKey(key string) string {
+	b := make([]byte, 0, len(key)+8)
+	for i := 0; i < len(key); {
+		r, size := utf8.DecodeRuneInString(key[i:])
+		if r >= 0x20 && r != 0x7f && r != '%' && (r != utf8.RuneError || size > 1) && enc.validKeyRune(r) {
+			b = append(b, key[i:i+size]...)
+		} else {
+			for j := i; j < i+size; j++ {
+				b = append(b, '%', upperhex[key[j]>>4], upperhex[key[j]&0xF])
+			}
+		}
+		i += size
+	}
+	return string(b)
+}
@@ -0,0 +1,151 @@
+package ltsv_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestInvalidKeyPolicy(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.LevelKey = ""
+
+	testCases := []struct {
+		opts []ltsv.Option
+		want string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.InvalidKeyPolicy(ltsv.ReplaceInvalidKey)},
+			want: "msg:hello\tid:1\thost_port:localhost:80\tn:2\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.InvalidKeyPolicy(ltsv.EscapeInvalidKey)},
+			want: "msg:hello\tid:1\thost%3Aport:localhost:80\tn:2\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.InvalidKeyPolicy(ltsv.DropInvalidKey)},
+			want: "msg:hello\tid:1\tn:2\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.InvalidKeyPolicy(ltsv.FallbackInvalidKey)},
+			want: "msg:hello\tid:1\tltsvKeyError:host:port\tltsvInvalidKey:localhost:80\tn:2\n",
+		},
+		{
+			opts: []ltsv.Option{
+				ltsv.InvalidKeyPolicy(ltsv.FallbackInvalidKey),
+				ltsv.FallbackKey("invalid"),
+			},
+			want: "msg:hello\tid:1\tltsvKeyError:host:port\tinvalid:localhost:80\tn:2\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{
+			zap.Int("id", 1),
+			zap.String("host:port", "localhost:80"),
+			zap.Int("n", 2),
+		})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
+
+func TestInvalidKeyPolicyConfigKeys(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.LevelKey = "lv:l"
+	cfg.MessageKey = "m:sg"
+
+	enc := ltsv.NewLTSVEncoder(cfg, ltsv.InvalidKeyPolicy(ltsv.DropInvalidKey)).Clone()
+	enc.AddString("a:b", "with")
+	enc.AddString("c", "with")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{zap.Int("n", 1)})
+	if err != nil {
+		t.Fatalf("failed to encode entry; err=%+v", err)
+	}
+	if got, want := buf.String(), "c:with\tn:1\n"; got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+}
+
+func TestInvalidKeyPanics(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	enc := ltsv.NewLTSVEncoder(cfg)
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic for key with colon")
+		}
+	}()
+	enc.AddString("host:port", "localhost:80")
+}
+
+func TestStrictKeys(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.LevelKey = ""
+
+	testCases := []struct {
+		opts []ltsv.Option
+		want string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.StrictKeys(), ltsv.InvalidKeyPolicy(ltsv.ReplaceInvalidKey)},
+			want: "msg:hello\tuser_name:alice\tcaf_:latte\t_:empty\tx-y.z_0:ok\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.StrictKeys(), ltsv.InvalidKeyPolicy(ltsv.DropInvalidKey)},
+			want: "msg:hello\tx-y.z_0:ok\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.InvalidKeyPolicy(ltsv.DropInvalidKey)},
+			want: "msg:hello\tuser name:alice\tcafé:latte\t:empty\tx-y.z_0:ok\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{
+			zap.String("user name", "alice"),
+			zap.String("café", "latte"),
+			zap.String("", "empty"),
+			zap.String("x-y.z_0", "ok"),
+		})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
+
+func TestKeyWarningOutput(t *testing.T) {
+	var out bytes.Buffer
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	enc := ltsv.NewLTSVEncoder(cfg,
+		ltsv.StrictKeys(),
+		ltsv.InvalidKeyPolicy(ltsv.ReplaceInvalidKey),
+		ltsv.KeyWarningOutput(zapcore.AddSync(&out)),
+	)
+	enc.AddString("ok", "1")
+	enc.AddString("not ok", "2")
+	if got, want := out.String(), `ltsv: invalid key "not ok"`; !strings.Contains(got, want) {
+		t.Errorf("got=%q, want to contain %q", got, want)
+	}
+	if got, want := strings.Count(out.String(), "\n"), 1; got != want {
+		t.Errorf("got %d warnings, want %d", got, want)
+	}
+	stamp, _, _ := strings.Cut(out.String(), " ")
+	if _, err := time.Parse(time.RFC3339, stamp); err != nil || !strings.HasSuffix(stamp, "Z") {
+		t.Errorf("got time %q, want RFC 3339 in UTC", stamp)
+	}
+}
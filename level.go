@@ -0,0 +1,179 @@
+package ltsv
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSeverityKey is the conventional label of the syslog severity
+// written with the SeverityKey option.
+const DefaultSeverityKey = "severity"
+
+// SyslogSeverity returns the syslog severity of l as defined by RFC 5424:
+//
+//	DebugLevel   7 (debug)
+//	InfoLevel    6 (informational)
+//	WarnLevel    4 (warning)
+//	ErrorLevel   3 (error)
+//	DPanicLevel  2 (critical)
+//	PanicLevel   1 (alert)
+//	FatalLevel   0 (emergency)
+//
+// Levels below DebugLevel are debug, and levels above FatalLevel are
+// emergency.
+func SyslogSeverity(l zapcore.Level) int {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return 7
+	case l == zapcore.InfoLevel:
+		return 6
+	case l == zapcore.WarnLevel:
+		return 4
+	case l == zapcore.ErrorLevel:
+		return 3
+	case l == zapcore.DPanicLevel:
+		return 2
+	case l == zapcore.PanicLevel:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SyslogSeverityLevelEncoder serializes a Level as its syslog severity,
+// e.g. InfoLevel is serialized to 6. See SyslogSeverity.
+func SyslogSeverityLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt(SyslogSeverity(l))
+}
+
+// ShortLevelEncoder serializes a Level to a short upper-case form, e.g.
+// InfoLevel is serialized to "I". DPanicLevel is serialized to "DP" to
+// tell it from DebugLevel.
+func ShortLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(shortLevel(l))
+}
+
+func shortLevel(l zapcore.Level) string {
+	switch l {
+	case zapcore.DebugLevel:
+		return "D"
+	case zapcore.InfoLevel:
+		return "I"
+	case zapcore.WarnLevel:
+		return "W"
+	case zapcore.ErrorLevel:
+		return "E"
+	case zapcore.DPanicLevel:
+		return "DP"
+	case zapcore.PanicLevel:
+		return "P"
+	case zapcore.FatalLevel:
+		return "F"
+	default:
+		return l.CapitalString()
+	}
+}
+
+// A LevelFormat selects a level encoder in the serializable EncoderOptions,
+// where the level encoders of this package cannot be set in the
+// EncoderConfig.
+type LevelFormat int
+
+const (
+	// ConfiguredLevel writes levels with EncodeLevel of the EncoderConfig.
+	// This is the default format.
+	ConfiguredLevel LevelFormat = iota
+	// SyslogSeverityLevel writes levels with SyslogSeverityLevelEncoder.
+	SyslogSeverityLevel
+	// ShortLevel writes levels with ShortLevelEncoder.
+	ShortLevel
+)
+
+// String returns a lower-case ASCII representation of the format.
+func (f LevelFormat) String() string {
+	switch f {
+	case ConfiguredLevel:
+		return "config"
+	case SyslogSeverityLevel:
+		return "syslog"
+	case ShortLevel:
+		return "short"
+	default:
+		return fmt.Sprintf("LevelFormat(%d)", int(f))
+	}
+}
+
+// MarshalText marshals the format to text. See String.
+func (f LevelFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText unmarshals text to a format. Valid values are "config",
+// "syslog" and "short".
+func (f *LevelFormat) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "config", "":
+		*f = ConfiguredLevel
+	case "syslog":
+		*f = SyslogSeverityLevel
+	case "short":
+		*f = ShortLevel
+	default:
+		return fmt.Errorf("ltsv: unknown level format %q", text)
+	}
+	return nil
+}
+
+// encoder returns the level encoder of the format, or nil for
+// ConfiguredLevel.
+func (f LevelFormat) encoder() zapcore.LevelEncoder {
+	switch f {
+	case SyslogSeverityLevel:
+		return SyslogSeverityLevelEncoder
+	case ShortLevel:
+		return ShortLevelEncoder
+	default:
+		return nil
+	}
+}
+
+// LevelEncoder makes the encoder write levels with e instead of
+// EncodeLevel of the EncoderConfig.
+func LevelEncoder(e zapcore.LevelEncoder) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.encodeLevel = e
+	})
+}
+
+// SeverityKey makes the encoder write the syslog severity of the level
+// under key right after the level label, so that both the level and the
+// severity are written, e.g. "level:error\tseverity:3". The severity is
+// written even if the LevelKey of the EncoderConfig is empty.
+func SeverityKey(key string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.severityKey = key
+	})
+}
+
+// addLevel writes the level and severity labels of an entry.
+func (enc *ltsvEncoder) addLevel(l zapcore.Level) {
+	if enc.LevelKey != "" && enc.addKey(enc.LevelKey) {
+		encodeLevel := enc.EncodeLevel
+		if enc.opts.encodeLevel != nil {
+			encodeLevel = enc.opts.encodeLevel
+		}
+		cur := enc.buf.Len()
+		if encodeLevel != nil {
+			encodeLevel(l, enc)
+		}
+		if cur == enc.buf.Len() {
+			// User-supplied EncodeLevel was a no-op. Fall back to strings to keep
+			// output JSON valid.
+			enc.AppendString(l.String())
+		}
+	}
+	if enc.opts.severityKey != "" {
+		enc.AddInt(enc.opts.severityKey, SyslogSeverity(l))
+	}
+}
@@ -0,0 +1,98 @@
+package ltsv_test
+
+import (
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelEncoders(t *testing.T) {
+	testCases := []struct {
+		encodeLevel zapcore.LevelEncoder
+		opts        []ltsv.Option
+		level       zapcore.Level
+		want        string
+	}{
+		{
+			encodeLevel: ltsv.SyslogSeverityLevelEncoder,
+			level:       zapcore.ErrorLevel,
+			want:        "level:3\tmsg:hello\n",
+		},
+		{
+			encodeLevel: ltsv.SyslogSeverityLevelEncoder,
+			level:       zapcore.InfoLevel,
+			want:        "level:6\tmsg:hello\n",
+		},
+		{
+			encodeLevel: ltsv.ShortLevelEncoder,
+			level:       zapcore.ErrorLevel,
+			want:        "level:E\tmsg:hello\n",
+		},
+		{
+			encodeLevel: ltsv.ShortLevelEncoder,
+			level:       zapcore.DPanicLevel,
+			want:        "level:DP\tmsg:hello\n",
+		},
+		{
+			encodeLevel: zapcore.LowercaseLevelEncoder,
+			opts:        []ltsv.Option{ltsv.SeverityKey(ltsv.DefaultSeverityKey)},
+			level:       zapcore.WarnLevel,
+			want:        "level:warn\tseverity:4\tmsg:hello\n",
+		},
+		{
+			encodeLevel: zapcore.LowercaseLevelEncoder,
+			opts:        []ltsv.Option{ltsv.LevelEncoder(ltsv.ShortLevelEncoder), ltsv.SeverityKey("sev")},
+			level:       zapcore.FatalLevel,
+			want:        "level:F\tsev:0\tmsg:hello\n",
+		},
+		{
+			encodeLevel: func(zapcore.Level, zapcore.PrimitiveArrayEncoder) {},
+			level:       zapcore.DebugLevel,
+			want:        "level:debug\tmsg:hello\n",
+		},
+	}
+	for _, tc := range testCases {
+		cfg := ltsv.NewDevelopmentEncoderConfig()
+		cfg.TimeKey = ""
+		cfg.EncodeLevel = tc.encodeLevel
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Level: tc.level, Message: "hello"}, nil)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
+
+func TestLevelDecoders(t *testing.T) {
+	testCases := []struct {
+		encode zapcore.LevelEncoder
+		decode ltsv.LevelDecoder
+	}{
+		{encode: ltsv.SyslogSeverityLevelEncoder, decode: ltsv.SyslogSeverityLevelDecoder},
+		{encode: ltsv.ShortLevelEncoder, decode: ltsv.ShortLevelDecoder},
+	}
+	for _, tc := range testCases {
+		for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+			cfg := ltsv.NewProductionEncoderConfig()
+			cfg.TimeKey = ""
+			cfg.MessageKey = ""
+			cfg.EncodeLevel = tc.encode
+			buf, err := ltsv.NewLTSVEncoder(cfg).EncodeEntry(zapcore.Entry{Level: l}, nil)
+			if err != nil {
+				t.Fatalf("failed to encode entry; err=%+v", err)
+			}
+			s := buf.String()
+			got, err := tc.decode(s[len("level:") : len(s)-1])
+			if err != nil {
+				t.Fatalf("failed to decode level %q; err=%+v", s, err)
+			}
+			if got != l {
+				t.Errorf("got=%v, want=%v", got, l)
+			}
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package ltsv
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// DefaultOverflowKey is the label used by OverflowToLabel unless another
+// one is set with the OverflowKey option.
+const DefaultOverflowKey = "ltsvOverflow"
+
+// An OverflowAction decides what the encoder does with a value which
+// exceeds a size limit.
+type OverflowAction int
+
+const (
+	// TruncateOverflow cuts the value and appends a marker like
+	// "…(truncated 123 bytes)", where the number is the size of the removed
+	// part. The marker counts towards the limit. If the limit is too small
+	// for the marker, the field is dropped. Values written as JSON are not
+	// cut, since that would leave invalid JSON, but handled as with
+	// OverflowToLabel. This is the default action.
+	TruncateOverflow OverflowAction = iota
+	// DropOverflow drops the field.
+	DropOverflow
+	// OverflowToLabel drops the field and records its label and size, as in
+	// "body=41943040", in the overflow label at the end of the line.
+	// Multiple fields are separated by commas.
+	OverflowToLabel
+)
+
+// String returns a lower-case ASCII representation of the action.
+func (a OverflowAction) String() string {
+	switch a {
+	case TruncateOverflow:
+		return "truncate"
+	case DropOverflow:
+		return "drop"
+	case OverflowToLabel:
+		return "label"
+	default:
+		return fmt.Sprintf("OverflowAction(%d)", int(a))
+	}
+}
+
+// MarshalText marshals the action to text. See String.
+func (a OverflowAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText unmarshals text to an action. Valid values are
+// "truncate", "drop" and "label".
+func (a *OverflowAction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "truncate", "":
+		*a = TruncateOverflow
+	case "drop":
+		*a = DropOverflow
+	case "label":
+		*a = OverflowToLabel
+	default:
+		return fmt.Errorf("ltsv: unknown overflow action %q", text)
+	}
+	return nil
+}
+
+// MaxValueBytes limits the size of each top-level value to n bytes after
+// escaping. Nested values written as JSON count as a single value.
+// A limit of zero or less means no limit.
+func MaxValueBytes(n int) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.maxValueBytes = n
+	})
+}
+
+// MaxValueBytesByKey overrides the limit of MaxValueBytes for the given
+// labels. Labels of flattened values include their prefix. A limit of zero
+// or less means no limit for the label.
+func MaxValueBytesByKey(limits map[string]int) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.maxValueBytesByKey = make(map[string]int, len(limits))
+		for k, v := range limits {
+			opts.maxValueBytesByKey[k] = v
+		}
+	})
+}
+
+// MaxLineBytes limits the size of each line, including the trailing
+// newline, to n bytes. When a value would make the line longer, the
+// overflow action is applied to it with the remaining space as the limit.
+// Fields added with With are checked again at their position in each line.
+// The stacktrace is checked like any other value, but
+// the overflow label is not. A limit of zero or less means no limit.
+func MaxLineBytes(n int) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.maxLineBytes = n
+	})
+}
+
+// OnOverflow sets the action for values exceeding a size limit.
+func OnOverflow(action OverflowAction) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.overflowAction = action
+	})
+}
+
+// OverflowKey sets the label used by OverflowToLabel.
+func OverflowKey(key string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.overflowKey = key
+	})
+}
+
+// limited reports whether any size limit is set.
+func (o *encoderOptions) limited() bool {
+	return o.maxValueBytes > 0 || len(o.maxValueBytesByKey) > 0 || o.maxLineBytes > 0
+}
+
+// startValue records the position of a top-level label written by addKey
+// from keyStart, so that finishValue can apply the limits to its value.
+func (enc *ltsvEncoder) startValue(keyStart int, key string) {
+	if !enc.opts.limited() {
+		return
+	}
+	enc.pending = true
+	enc.valueJSON = false
+	enc.valueKey = enc.flatPrefix + key
+	enc.keyStart = keyStart
+	enc.valueStart = enc.buf.Len()
+}
+
+// finishValue applies the limits to the value of the last top-level label,
+// which must be complete.
+func (enc *ltsvEncoder) finishValue() {
+	if !enc.pending {
+		return
+	}
+	enc.pending = false
+
+	size := enc.buf.Len() - enc.valueStart
+	orig, kept := size, size
+	if enc.origSize > 0 {
+		orig, kept = enc.origSize, enc.keptSize
+		enc.origSize = 0
+	}
+	max := -1
+	if n, ok := enc.opts.maxValueBytesByKey[enc.valueKey]; ok {
+		if n > 0 {
+			max = n
+		}
+	} else if enc.opts.maxValueBytes > 0 {
+		max = enc.opts.maxValueBytes
+	}
+	if n := enc.opts.maxLineBytes; n > 0 && enc.inLine {
+		room := n - 1 - enc.valueStart
+		if room < 0 {
+			room = 0
+		}
+		if max < 0 || room < max {
+			max = room
+		}
+	}
+	if max < 0 || size <= max {
+		return
+	}
+
+	action := enc.opts.overflowAction
+	if action == TruncateOverflow && enc.valueJSON {
+		action = OverflowToLabel
+	}
+	switch action {
+	case DropOverflow:
+	case OverflowToLabel:
+		if enc.overflowed != "" {
+			enc.overflowed += ","
+		}
+		enc.overflowed += enc.valueKey + "=" + strconv.Itoa(orig)
+	default:
+		markerLen := len(truncatedPrefix) + len(strconv.Itoa(orig)) + len(truncatedSuffix)
+		if max >= markerLen {
+			// A value which was cut before is cut again within the part
+			// kept then, and the marker counts from its original size.
+			end := enc.valueStart + max - markerLen
+			if end > enc.valueStart+kept {
+				end = enc.valueStart + kept
+			}
+			cut := enc.safeCut(enc.valueStart, end)
+			enc.truncateBuf(cut)
+			enc.buf.AppendString(truncatedPrefix)
+			enc.buf.AppendInt(int64(orig - (cut - enc.valueStart)))
+			enc.buf.AppendString(truncatedSuffix)
+			if !enc.inLine {
+				enc.truncations = append(enc.truncations, truncation{
+					valueStart: enc.valueStart,
+					size:       orig,
+					kept:       cut - enc.valueStart,
+				})
+			}
+			return
+		}
+	}
+	enc.truncateBuf(enc.keyStart)
+}
+
+// A truncation records a value cut by TruncateOverflow in a context
+// encoder: it started at valueStart and had size bytes, of which kept were
+// kept.
+type truncation struct {
+	valueStart int
+	size       int
+	kept       int
+}
+
+const (
+	truncatedPrefix = "…(truncated "
+	truncatedSuffix = " bytes)"
+)
+
+// addOverflowLabel writes the overflow label for OverflowToLabel. It is
+// not subject to the limits.
+func (enc *ltsvEncoder) addOverflowLabel() {
+	if enc.overflowed == "" {
+		return
+	}
+	enc.AddString(enc.opts.overflowKey, enc.overflowed)
+	enc.pending = false
+}
+
+// safeCut returns the largest position up to end at which the value
+// starting at start can be cut without splitting an escape sequence or a
+// UTF-8 encoded rune.
+func (enc *ltsvEncoder) safeCut(start, end int) int {
+	b := enc.buf.Bytes()
+	escape := byte('\\')
+	if enc.opts.escapeMode == PercentEscape && !enc.valueJSON {
+		escape = '%'
+	}
+	i := start
+	for i < end {
+		n := tokenLen(b[i:], escape)
+		if i+n > end {
+			break
+		}
+		i += n
+	}
+	return i
+}
+
+// tokenLen returns the length of the escape sequence or rune at the start
+// of b.
+func tokenLen(b []byte, escape byte) int {
+	n := 1
+	switch {
+	case b[0] == escape && escape == '%':
+		n = 3
+	case b[0] == escape && len(b) > 1 && b[1] == 'u':
+		n = 6
+	case b[0] == escape:
+		n = 2
+	case b[0] >= utf8.RuneSelf:
+		_, n = utf8.DecodeRune(b)
+	}
+	if n > len(b) {
+		n = len(b)
+	}
+	return n
+}
+
+// truncateBuf shortens the buffer to n bytes.
+func (enc *ltsvEncoder) truncateBuf(n int) {
+	b := enc.buf.Bytes()[:n]
+	enc.buf.Reset()
+	enc.buf.Write(b)
+}
+
+// addContext copies the labels added with With from enc to the line. With
+// MaxLineBytes, which does not apply to context encoders, the complete
+// labels are checked at their position in the line. A label which may continue in the fields stays pending.
+func (enc *ltsvEncoder) addContext(ctx *ltsvEncoder) {
+	b := ctx.buf.Bytes()
+	done := 0
+	if enc.opts.maxLineBytes > 0 {
+		done = len(b)
+		if ctx.pending {
+			done = ctx.keyStart
+		}
+		labelStart := 0
+		for labelStart < done {
+			label := b[labelStart:done]
+			if j := bytes.IndexByte(label, '\t'); j >= 0 {
+				label = label[:j]
+			}
+			keyStart := enc.buf.Len()
+			enc.addElementSeparator()
+			i := bytes.IndexByte(label, ':') + 1
+			enc.buf.Write(label[:i])
+			enc.startValue(keyStart, string(label[:i-1]))
+			enc.buf.Write(label[i:])
+			enc.valueJSON = isJSONValue(label[i:])
+			for _, t := range ctx.truncations {
+				if t.valueStart == labelStart+i {
+					enc.origSize, enc.keptSize = t.size, t.kept
+				}
+			}
+			enc.finishValue()
+			labelStart += len(label) + 1
+		}
+	}
+	rest := b[done:]
+	if len(rest) == 0 {
+		return
+	}
+	keyStart := enc.buf.Len()
+	enc.addElementSeparator()
+	offset := enc.buf.Len()
+	if done > 0 {
+		// Skip the separator before the pending label.
+		rest = rest[1:]
+		offset -= done + 1
+	} else if ctx.keyStart > 0 {
+		keyStart = offset + ctx.keyStart
+	}
+	enc.buf.Write(rest)
+	if ctx.pending {
+		// The last label of the context may continue in the fields.
+		enc.pending = true
+		enc.valueJSON = ctx.valueJSON
+		enc.valueKey = ctx.valueKey
+		enc.keyStart = keyStart
+		enc.valueStart = offset + ctx.valueStart
+	}
+}
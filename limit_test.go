@@ -0,0 +1,189 @@
+package ltsv_test
+
+import (
+	"strings"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLimits(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts   []ltsv.Option
+		msg    string
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30)},
+			fields: []zapcore.Field{zap.String("body", strings.Repeat("a", 100)), zap.Int("n", 1)},
+			want:   "level:info\tmsg:hello\tbody:aaaaaa…(truncated 94 bytes)\tn:1\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30)},
+			fields: []zapcore.Field{zap.String("body", strings.Repeat("あ", 20))},
+			want:   "level:info\tmsg:hello\tbody:ああ…(truncated 54 bytes)\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30)},
+			fields: []zapcore.Field{zap.ByteString("body", []byte(strings.Repeat("\t", 20)))},
+			want:   "level:info\tmsg:hello\tbody:\\t\\t\\t…(truncated 34 bytes)\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30), ltsv.ValueEscaping(ltsv.PercentEscape)},
+			fields: []zapcore.Field{zap.String("body", strings.Repeat("\t", 20))},
+			want:   "level:info\tmsg:hello\tbody:%09%09…(truncated 54 bytes)\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30)},
+			fields: []zapcore.Field{zap.Strings("body", []string{strings.Repeat("a", 30), "b"})},
+			want:   "level:info\tmsg:hello\tltsvOverflow:body=38\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30), ltsv.MaxValueBytesByKey(map[string]int{"msg": 0, "req": 0})},
+			msg:    strings.Repeat("m", 40),
+			fields: []zapcore.Field{zap.Namespace("req"), zap.String("body", strings.Repeat("a", 40))},
+			want:   "level:info\tmsg:" + strings.Repeat("m", 40) + "\treq:{\"body\":\"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\"}\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytesByKey(map[string]int{"req.body": 26}), ltsv.Flatten(".", 0)},
+			fields: []zapcore.Field{zap.Namespace("req"), zap.String("body", strings.Repeat("a", 40))},
+			want:   "level:info\tmsg:hello\treq.body:aaa…(truncated 37 bytes)\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(10)},
+			fields: []zapcore.Field{zap.String("body", strings.Repeat("a", 100)), zap.Int("n", 1)},
+			want:   "level:info\tmsg:hello\tn:1\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30), ltsv.OnOverflow(ltsv.DropOverflow)},
+			fields: []zapcore.Field{zap.String("body", strings.Repeat("a", 100)), zap.Int("n", 1)},
+			want:   "level:info\tmsg:hello\tn:1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.MaxValueBytes(30), ltsv.OnOverflow(ltsv.OverflowToLabel)},
+			fields: []zapcore.Field{
+				zap.String("body", strings.Repeat("a", 100)),
+				zap.Int("n", 1),
+				zap.String("resp", strings.Repeat("b", 50)),
+			},
+			want: "level:info\tmsg:hello\tn:1\tltsvOverflow:body=100,resp=50\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.MaxLineBytes(60)},
+			fields: []zapcore.Field{zap.Int("n", 1), zap.String("body", strings.Repeat("a", 100)), zap.Int("m", 2)},
+			want:   "level:info\tmsg:hello\tn:1\tbody:aaaaa…(truncated 95 bytes)\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		msg := tc.msg
+		if msg == "" {
+			msg = "hello"
+		}
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: msg}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestLimitsWith(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	enc := ltsv.NewLTSVEncoder(cfg, ltsv.MaxValueBytes(30)).Clone()
+	enc.AddString("a", strings.Repeat("a", 40))
+	enc.OpenNamespace("req")
+	enc.AddString("b", strings.Repeat("b", 10))
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{zap.String("c", strings.Repeat("c", 10))})
+	if err != nil {
+		t.Fatalf("failed to encode entry; err=%+v", err)
+	}
+	want := "level:info\tmsg:hello\ta:aaaaaaa…(truncated 33 bytes)\tltsvOverflow:req=35\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+}
+
+func TestMaxLineBytesWithTruncated(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts []ltsv.Option
+		want string
+	}{
+		{
+			want: "level:info\tmsg:hello\ts:aaaaaaaaaaaaa…(truncated 72 bytes)\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.MaxValueBytes(50)},
+			want: "level:info\tmsg:hello\ts:aaaaaaaaaaaaa…(truncated 72 bytes)\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, append([]ltsv.Option{ltsv.MaxLineBytes(60)}, tc.opts...)...).Clone()
+		enc.AddString("s", strings.Repeat("a", 85))
+		enc.AddString("t", "x")
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, nil)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
+
+func TestMaxLineBytesWith(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts   []ltsv.Option
+		with   []zapcore.Field
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			with: []zapcore.Field{zap.String("a", strings.Repeat("a", 30)), zap.Int("n", 1)},
+			want: "level:info\tmsg:hello\tn:1\n",
+		},
+		{
+			with:   []zapcore.Field{zap.Int("n", 1), zap.Namespace("req")},
+			fields: []zapcore.Field{zap.String("b", strings.Repeat("b", 30))},
+			want:   "level:info\tmsg:hello\tn:1\tltsvOverflow:req=38\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.OnOverflow(ltsv.DropOverflow)},
+			with: []zapcore.Field{zap.Int("n", 1), zap.Strings("ids", []string{strings.Repeat("i", 30)})},
+			want: "level:info\tmsg:hello\tn:1\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, append([]ltsv.Option{ltsv.MaxLineBytes(40)}, tc.opts...)...).Clone()
+		for _, f := range tc.with {
+			f.AddTo(enc)
+		}
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		got := buf.String()
+		if got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+		if !strings.Contains(got, "ltsvOverflow") && len(got) > 40 {
+			t.Errorf("line of %d bytes exceeds the limit: %q", len(got), got)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Package ltsvjson converts between LTSV lines written by the zap LTSV
+// encoder and JSON objects written by the zap JSON encoder.
+package ltsvjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ToJSON converts a record to a JSON object with the labels in the same
+// order.
+//
+// Values which are JSON objects or arrays, as written by the encoder for
+// nested values, are embedded as they are. Values which are JSON numbers,
+// true, false or null keep that type. Other values become strings.
+//
+// If separator is not empty, labels containing it are nested into objects,
+// reversing ltsv.Flatten; objects whose keys are 0, 1, 2 and so on become
+// arrays. A label which conflicts with a nested one is kept as it is.
+// Duplicate labels result in duplicate keys.
+func ToJSON(rec ltsv.Record, separator string) ([]byte, error) {
+	root := &node{}
+	for _, p := range rec {
+		if separator == "" || !root.insert(strings.Split(p.Label, separator), p.Value) {
+			root.add(p.Label, &node{value: p.Value, leaf: true})
+		}
+	}
+	var buf bytes.Buffer
+	if err := root.writeJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// node is a JSON value being built by ToJSON. Like in the LTSV line,
+// an object may have duplicate keys.
+type node struct {
+	leaf  bool
+	value string
+
+	members []member
+	objects map[string]*node // nested objects by key
+	leaves  map[string]bool  // keys of leaf members
+}
+
+type member struct {
+	key  string
+	node *node
+}
+
+func (n *node) add(key string, child *node) {
+	n.members = append(n.members, member{key: key, node: child})
+	if child.leaf {
+		if n.leaves == nil {
+			n.leaves = make(map[string]bool)
+		}
+		n.leaves[key] = true
+	} else {
+		if n.objects == nil {
+			n.objects = make(map[string]*node)
+		}
+		n.objects[key] = child
+	}
+}
+
+// insert adds value at path. It returns false if the path conflicts with
+// a member which has already been added.
+func (n *node) insert(path []string, value string) bool {
+	if !n.canInsert(path) {
+		return false
+	}
+	for _, key := range path[:len(path)-1] {
+		child, ok := n.objects[key]
+		if !ok {
+			child = &node{}
+			n.add(key, child)
+		}
+		n = child
+	}
+	n.add(path[len(path)-1], &node{value: value, leaf: true})
+	return true
+}
+
+func (n *node) canInsert(path []string) bool {
+	for i, key := range path {
+		if i == len(path)-1 {
+			return n.objects[key] == nil
+		}
+		if n.leaves[key] {
+			return false
+		}
+		child, ok := n.objects[key]
+		if !ok {
+			return true
+		}
+		n = child
+	}
+	return true
+}
+
+func (n *node) isArray() bool {
+	if len(n.members) == 0 {
+		return false
+	}
+	for i, m := range n.members {
+		if m.key != strconv.Itoa(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *node) writeJSON(buf *bytes.Buffer) error {
+	if n.leaf {
+		return writeJSONValue(buf, n.value)
+	}
+	array := n.isArray()
+	if array {
+		buf.WriteByte('[')
+	} else {
+		buf.WriteByte('{')
+	}
+	for i, m := range n.members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if !array {
+			k, err := json.Marshal(m.key)
+			if err != nil {
+				return err
+			}
+			buf.Write(k)
+			buf.WriteByte(':')
+		}
+		if err := m.node.writeJSON(buf); err != nil {
+			return err
+		}
+	}
+	if array {
+		buf.WriteByte(']')
+	} else {
+		buf.WriteByte('}')
+	}
+	return nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, s string) error {
+	switch {
+	case s == "true" || s == "false" || s == "null" || isJSONNumber(s):
+		buf.WriteString(s)
+		return nil
+	case len(s) >= 2 && (s[0] == '{' || s[0] == '[') && json.Valid([]byte(s)):
+		buf.WriteString(s)
+		return nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+// isJSONNumber reports whether s is a number in the JSON grammar.
+func isJSONNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(s) && s[i] == '0':
+		i++
+	case i < len(s) && '1' <= s[i] && s[i] <= '9':
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	default:
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		start := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		start := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// ToLTSV converts a JSON object to an LTSV line without the trailing
+// newline. The line is written by the LTSV encoder created with opts, so
+// the same escaping, key policy and flattening apply. The members are
+// written in the same order as in the object.
+//
+// Keys which are not valid labels are replaced as with
+// ltsv.ReplaceInvalidKey unless opts set another key policy. If the key
+// policy panics, the panic is returned as an error.
+func ToLTSV(object []byte, opts ...ltsv.Option) (line []byte, err error) {
+	dec := json.NewDecoder(bytes.NewReader(object))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(jsonObject)
+	if !ok {
+		return nil, errors.New("ltsvjson: not a JSON object")
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, errors.New("ltsvjson: extra data after JSON object")
+	}
+
+	opts = append([]ltsv.Option{ltsv.InvalidKeyPolicy(ltsv.ReplaceInvalidKey)}, opts...)
+	enc := ltsv.NewLTSVEncoder(zapcore.EncoderConfig{}, opts...)
+	defer func() {
+		if r := recover(); r != nil {
+			line, err = nil, fmt.Errorf("ltsvjson: %v", r)
+		}
+	}()
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Inline(obj)})
+	if err != nil {
+		return nil, err
+	}
+	line = append([]byte(nil), bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})...)
+	buf.Free()
+	return line, nil
+}
+
+// jsonObject is a JSON object which keeps the order of its members.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// jsonArray is a JSON array of values as returned by decodeValue.
+type jsonArray []interface{}
+
+func (o jsonObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, m := range o {
+		if err := addValue(enc, m.key, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a jsonArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range a {
+		if err := appendValue(enc, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addValue(enc zapcore.ObjectEncoder, key string, v interface{}) error {
+	switch v := v.(type) {
+	case jsonObject:
+		return enc.AddObject(key, v)
+	case jsonArray:
+		return enc.AddArray(key, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			enc.AddInt64(key, i)
+		} else if f, err := v.Float64(); err == nil {
+			enc.AddFloat64(key, f)
+		} else {
+			return enc.AddReflected(key, v)
+		}
+	case string:
+		enc.AddString(key, v)
+	case bool:
+		enc.AddBool(key, v)
+	default:
+		return enc.AddReflected(key, v)
+	}
+	return nil
+}
+
+func appendValue(enc zapcore.ArrayEncoder, v interface{}) error {
+	switch v := v.(type) {
+	case jsonObject:
+		return enc.AppendObject(v)
+	case jsonArray:
+		return enc.AppendArray(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			enc.AppendInt64(i)
+		} else if f, err := v.Float64(); err == nil {
+			enc.AppendFloat64(f)
+		} else {
+			return enc.AppendReflected(v)
+		}
+	case string:
+		enc.AppendString(v)
+	case bool:
+		enc.AppendBool(v)
+	default:
+		return enc.AppendReflected(v)
+	}
+	return nil
+}
+
+// decodeValue decodes the next JSON value from dec, keeping the order of
+// object members.
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("ltsvjson: %v", err)
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("ltsvjson: %v", err)
+			}
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{key: keyTok.(string), value: v})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("ltsvjson: %v", err)
+		}
+		return obj, nil
+	case json.Delim('['):
+		arr := jsonArray{}
+		for dec.More() {
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("ltsvjson: %v", err)
+		}
+		return arr, nil
+	default:
+		return tok, nil
+	}
+}
@@ -0,0 +1,106 @@
+package ltsvjson_test
+
+import (
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"github.com/hnakamur/zap-ltsv/ltsvjson"
+)
+
+func TestToJSON(t *testing.T) {
+	testCases := []struct {
+		line      string
+		separator string
+		want      string
+	}{
+		{
+			line: "level:info\tmsg:hello \\\"world\\\"\tn:-1.5e3\tok:true\tnil:null\tzip:01234\tusers:[{\"name\":\"a\"}]\tbad:[1,",
+			want: `{"level":"info","msg":"hello \"world\"","n":-1.5e3,"ok":true,"nil":null,"zip":"01234","users":[{"name":"a"}],"bad":"[1,"}`,
+		},
+		{
+			line:      "msg:hi\thttp.method:GET\thttp.status:200\tusers.0.name:a\tusers.1.name:b\thttp:x\tmsg:again",
+			separator: ".",
+			want:      `{"msg":"hi","http":{"method":"GET","status":200},"users":[{"name":"a"},{"name":"b"}],"http":"x","msg":"again"}`,
+		},
+		{
+			line: "http.method:GET",
+			want: `{"http.method":"GET"}`,
+		},
+	}
+	for _, tc := range testCases {
+		rec, err := ltsv.ParseRecord([]byte(tc.line), ltsv.JSONEscape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ltsvjson.ToJSON(rec, tc.separator)
+		if err != nil {
+			t.Errorf("line=%q, unexpected error: %v", tc.line, err)
+		} else if string(got) != tc.want {
+			t.Errorf("got=%s, want=%s", got, tc.want)
+		}
+	}
+}
+
+func TestToLTSV(t *testing.T) {
+	testCases := []struct {
+		json string
+		opts []ltsv.Option
+		want string
+	}{
+		{
+			json: `{"level":"info","msg":"a\tb","n":2,"f":1.5,"ok":false,"nil":null,"http":{"method":"GET","codes":[200,304]}}`,
+			want: "level:info\tmsg:a\\tb\tn:2\tf:1.5\tok:false\tnil:null\thttp:{\"method\":\"GET\",\"codes\":[200,304]}",
+		},
+		{
+			json: `{"msg":"a\tb","http":{"method":"GET","codes":[200,304]}}`,
+			opts: []ltsv.Option{ltsv.Flatten(".", 0), ltsv.ValueEscaping(ltsv.PercentEscape)},
+			want: "msg:a%09b\thttp.method:GET\thttp.codes.0:200\thttp.codes.1:304",
+		},
+		{
+			json: `{"host:port":"a"}`,
+			want: "host_port:a",
+		},
+	}
+	for _, tc := range testCases {
+		got, err := ltsvjson.ToLTSV([]byte(tc.json), tc.opts...)
+		if err != nil {
+			t.Errorf("json=%s, unexpected error: %v", tc.json, err)
+		} else if string(got) != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{`[1]`, `{"a":1} {}`, `{"a":`} {
+		if _, err := ltsvjson.ToLTSV([]byte(bad)); err == nil {
+			t.Errorf("json=%s, want error", bad)
+		}
+	}
+	if _, err := ltsvjson.ToLTSV([]byte(`{"host:port":"a"}`), ltsv.InvalidKeyPolicy(ltsv.PanicOnInvalidKey)); err == nil {
+		t.Error("want error with PanicOnInvalidKey")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := `{"level":"warn","msg":"x","latency":0.25,"user":{"id":7,"tags":["a","b"]}}`
+	for _, sep := range []string{"", "."} {
+		var opts []ltsv.Option
+		if sep != "" {
+			opts = append(opts, ltsv.Flatten(sep, 0))
+		}
+		line, err := ltsvjson.ToLTSV([]byte(in), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec, err := ltsv.ParseRecord(line, ltsv.JSONEscape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := ltsvjson.ToJSON(rec, sep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != in {
+			t.Errorf("separator=%q, got=%s, want=%s", sep, out, in)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package ltsv
+
+import (
+	"io"
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// An Option configures an LTSV encoder.
+type Option interface {
+	apply(*encoderOptions)
+}
+
+// optionFunc wraps a func so it satisfies the Option interface.
+type optionFunc func(*encoderOptions)
+
+func (f optionFunc) apply(opts *encoderOptions) {
+	f(opts)
+}
+
+// encoderOptions holds the settings shared by an encoder and its clones.
+// It must not be modified after the encoder is created.
+type encoderOptions struct {
+	spaced bool // include spaces after colons and commas in JSON
+
+	fieldOrder map[string]int
+
+	flatten      bool
+	flattenSep   string
+	flattenDepth int
+
+	keyPolicy        KeyPolicy
+	fallbackKey      string
+	strictKeys       bool
+	keyWarningOutput zapcore.WriteSyncer
+
+	escapeMode EscapeMode
+
+	encodeLevel zapcore.LevelEncoder
+	severityKey string
+
+	binaryEncoding      BinaryEncoding
+	binaryEncodingByKey map[string]BinaryEncoding
+
+	reflectStructs bool
+	structSep      string
+
+	newReflectedEncoder func(io.Writer) zapcore.ReflectedEncoder
+
+	splitCaller    bool
+	callerFileKey  string
+	callerLineKey  string
+	callerFuncKey  string
+	callerPrefixes []string
+	trimToModule   bool
+	modulePath     string
+
+	stackMode      StacktraceMode
+	stackSep       string
+	stackMaxFrames int
+	stackFilter    []string
+
+	errorFormat *ErrorFormat
+
+	floatFormat      FloatFormat
+	floatFormatByKey map[string]FloatFormat
+	nonFinite        NonFiniteFloats
+
+	maxValueBytes      int
+	maxValueBytesByKey map[string]int
+	maxLineBytes       int
+	overflowAction     OverflowAction
+	overflowKey        string
+
+	redaction *redactor
+
+	pseudonyms        *pseudonymizer
+	pseudonymKeyIDKey string
+}
+
+func newEncoderOptions(opts []Option) *encoderOptions {
+	o := &encoderOptions{
+		fallbackKey: DefaultFallbackKey,
+		overflowKey: DefaultOverflowKey,
+		stackSep:    DefaultStacktraceSeparator,
+
+		pseudonymKeyIDKey: DefaultPseudonymKeyIDKey,
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	return o
+}
+
+// Flatten makes the encoder write namespaces, objects and arrays as
+// separate labels instead of a single label with a JSON value.
+// The label of a nested value is the path from the top level joined with
+// separator, e.g. "http.method" or "users.0.name".
+//
+// Values nested more than maxDepth levels deep are written as JSON under
+// the label of the deepest level, in the same way as without Flatten.
+// A maxDepth of zero or less means no limit.
+func Flatten(separator string, maxDepth int) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.flatten = true
+		opts.flattenSep = separator
+		opts.flattenDepth = maxDepth
+	})
+}
+
+// SpacedJSON makes the encoder include a space after colons and commas in
+// nested JSON values, e.g. {"name": "jane", "age": 30}.
+func SpacedJSON() Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.spaced = true
+	})
+}
+
+// FieldOrder makes the encoder write the fields passed to a logging call
+// with the given keys first, in the order of keys. The other fields follow
+// in their original order. Fields added with With are written before them
+// as usual, since they are encoded when the child logger is created.
+func FieldOrder(keys ...string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.fieldOrder = make(map[string]int, len(keys))
+		for _, key := range keys {
+			if _, ok := opts.fieldOrder[key]; !ok {
+				opts.fieldOrder[key] = len(opts.fieldOrder)
+			}
+		}
+	})
+}
+
+// orderFields returns fields sorted by FieldOrder. The fields passed by
+// the caller are not modified.
+func (o *encoderOptions) orderFields(fields []zapcore.Field) []zapcore.Field {
+	if len(o.fieldOrder) == 0 || len(fields) < 2 {
+		return fields
+	}
+	rank := func(f zapcore.Field) int {
+		if r, ok := o.fieldOrder[f.Key]; ok {
+			return r
+		}
+		return len(o.fieldOrder)
+	}
+	sorted := make([]zapcore.Field, len(fields))
+	copy(sorted, fields)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
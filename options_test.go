@@ -0,0 +1,85 @@
+package ltsv_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOptions(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	testCases := []struct {
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.SpacedJSON()},
+			fields: []zapcore.Field{
+				zap.Dict("user", zap.String("name", "jane"), zap.Int("age", 30)),
+				zap.Ints("ids", []int{1, 2}),
+			},
+			want: "level:info\tmsg:hello\tuser:{\"name\": \"jane\", \"age\": 30}\tids:[1, 2]\n",
+		},
+		{
+			fields: []zapcore.Field{
+				zap.Dict("user", zap.String("name", "jane"), zap.Int("age", 30)),
+			},
+			want: "level:info\tmsg:hello\tuser:{\"name\":\"jane\",\"age\":30}\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.FieldOrder("status", "method", "status")},
+			fields: []zapcore.Field{
+				zap.String("path", "/"),
+				zap.String("method", "GET"),
+				zap.Int("size", 5),
+				zap.Int("status", 200),
+			},
+			want: "level:info\tmsg:hello\tstatus:200\tmethod:GET\tpath:/\tsize:5\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestRegisterLTSVEncoderName(t *testing.T) {
+	if err := ltsv.RegisterLTSVEncoderName("ltsv-test-flat", ltsv.Flatten(".", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := zap.Config{
+		Level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:      "ltsv-test-flat",
+		EncoderConfig: zapcore.EncoderConfig{MessageKey: "msg"},
+		OutputPaths:   []string{path},
+	}
+	logger, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hello", zap.Dict("req", zap.String("path", "/")))
+	logger.Sync()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "msg:hello\treq.path:/\n"; string(got) != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+}
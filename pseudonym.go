@@ -0,0 +1,243 @@
+package ltsv
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultPseudonymKeyIDKey is the label under which the key ID of a
+// Pseudonymizer is written unless another one is set with the
+// PseudonymKeyIDKey option.
+const DefaultPseudonymKeyIDKey = "pseudonymKeyID"
+
+// DefaultPseudonymLength is the number of characters of hex and base64url
+// pseudonyms when the Length of a Pseudonymizer is zero.
+const DefaultPseudonymLength = 16
+
+// A PseudonymFormat selects how a Pseudonymizer writes the HMAC of a value.
+type PseudonymFormat int
+
+const (
+	// HexPseudonym writes the HMAC in lower-case hexadecimal. This is the
+	// default format.
+	HexPseudonym PseudonymFormat = iota
+	// Base64URLPseudonym writes the HMAC in base64url without padding.
+	Base64URLPseudonym
+	// TokenPseudonym replaces each ASCII digit and letter of the value
+	// with one of the same class derived from the HMAC, and keeps the other
+	// characters, so "jane.doe@example.com" becomes something like
+	// "qxmt.vra@pkbwfnr.ajo". The token has the length of the value.
+	TokenPseudonym
+)
+
+// String returns a lower-case ASCII representation of the format.
+func (f PseudonymFormat) String() string {
+	switch f {
+	case HexPseudonym:
+		return "hex"
+	case Base64URLPseudonym:
+		return "base64url"
+	case TokenPseudonym:
+		return "token"
+	default:
+		return fmt.Sprintf("PseudonymFormat(%d)", int(f))
+	}
+}
+
+// MarshalText marshals the format to text. See String.
+func (f PseudonymFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText unmarshals text to a format. Valid values are "hex",
+// "base64url" and "token".
+func (f *PseudonymFormat) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "hex", "":
+		*f = HexPseudonym
+	case "base64url":
+		*f = Base64URLPseudonym
+	case "token":
+		*f = TokenPseudonym
+	default:
+		return fmt.Errorf("ltsv: unknown pseudonym format %q", text)
+	}
+	return nil
+}
+
+// A Pseudonymizer replaces values with a keyed HMAC-SHA256 of them, so
+// that equal values can be correlated across lines without revealing
+// them. Given the same secret, Verify tells whether a raw value matches a
+// pseudonym found in a log.
+//
+// KeyID identifies the secret, so that secrets can be rotated. The
+// encoder writes it in a separate label on each line containing
+// pseudonyms, unless it is empty.
+type Pseudonymizer struct {
+	KeyID  string
+	Secret []byte
+	Format PseudonymFormat
+	// Length is the number of characters kept of hex and base64url
+	// pseudonyms. Zero means DefaultPseudonymLength, and a negative
+	// length keeps the whole HMAC.
+	Length int
+}
+
+// Pseudonym returns the pseudonym of raw.
+func (p *Pseudonymizer) Pseudonym(raw string) string {
+	mac := p.mac(raw, 0)
+	var s string
+	switch p.Format {
+	case Base64URLPseudonym:
+		s = base64.RawURLEncoding.EncodeToString(mac)
+	case TokenPseudonym:
+		return p.token(raw, mac)
+	default:
+		b := make([]byte, 2*len(mac))
+		for i, c := range mac {
+			b[2*i] = hex[c>>4]
+			b[2*i+1] = hex[c&0xF]
+		}
+		s = string(b)
+	}
+	n := p.Length
+	if n == 0 {
+		n = DefaultPseudonymLength
+	}
+	if n > 0 && n < len(s) {
+		s = s[:n]
+	}
+	return s
+}
+
+// Verify reports whether pseudonym is the pseudonym of raw.
+func (p *Pseudonymizer) Verify(raw, pseudonym string) bool {
+	return hmac.Equal([]byte(p.Pseudonym(raw)), []byte(pseudonym))
+}
+
+// mac returns the HMAC of raw for the block-th part of a token. The first
+// block is the HMAC of raw itself.
+func (p *Pseudonymizer) mac(raw string, block uint32) []byte {
+	h := hmac.New(sha256.New, p.Secret)
+	if block > 0 {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], block)
+		h.Write(b[:])
+	}
+	h.Write([]byte(raw))
+	return h.Sum(nil)
+}
+
+// token returns the format-preserving pseudonym of raw, whose HMAC is mac.
+func (p *Pseudonymizer) token(raw string, mac []byte) string {
+	out := make([]byte, 0, len(raw))
+	var block uint32
+	// next returns a uniform random number below n from the bytes of the
+	// HMAC, discarding the bytes above the largest multiple of n.
+	next := func(n byte) byte {
+		limit := 256 / int(n) * int(n)
+		for {
+			if len(mac) == 0 {
+				block++
+				mac = p.mac(raw, block)
+			}
+			b := mac[0]
+			mac = mac[1:]
+			if int(b) < limit {
+				return b % n
+			}
+		}
+	}
+	for i := 0; i < len(raw); {
+		c := raw[i]
+		switch {
+		case '0' <= c && c <= '9':
+			out = append(out, '0'+next(10))
+		case 'a' <= c && c <= 'z':
+			out = append(out, 'a'+next(26))
+		case 'A' <= c && c <= 'Z':
+			out = append(out, 'A'+next(26))
+		default:
+			_, size := utf8.DecodeRuneInString(raw[i:])
+			out = append(out, raw[i:i+size]...)
+			i += size
+			continue
+		}
+		i++
+	}
+	return string(out)
+}
+
+// pseudonymizer holds the pseudonymization settings of an encoder.
+type pseudonymizer struct {
+	*Pseudonymizer
+	keys []string
+}
+
+// Pseudonymize makes the encoder replace string values of keys matching
+// any of the patterns with their pseudonym computed by p. Patterns are
+// matched in the same way as with RedactKeys. Redaction takes precedence
+// over pseudonymization.
+//
+// String values are replaced at any depth under a matching key, including
+// the elements of arrays and the strings in objects and reflected values,
+// while other values are kept. The key ID of p is written at the end of
+// each line containing pseudonyms.
+//
+// Repeated Pseudonymize options add their patterns to those of the
+// previous ones. Since a line has a single key ID, the values of all the
+// patterns are replaced by the Pseudonymizer of the last option.
+func Pseudonymize(p Pseudonymizer, patterns ...string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		var keys []string
+		if opts.pseudonyms != nil {
+			keys = opts.pseudonyms.keys
+		}
+		opts.pseudonyms = &pseudonymizer{
+			Pseudonymizer: &p,
+			keys:          append(append([]string(nil), keys...), patterns...),
+		}
+	})
+}
+
+// PseudonymKeyIDKey sets the label of the key ID written with pseudonyms.
+func PseudonymKeyIDKey(key string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.pseudonymKeyIDKey = key
+	})
+}
+
+// pseudonymizesKey reports whether the value of key must be replaced with
+// its pseudonym.
+func (enc *ltsvEncoder) pseudonymizesKey(key string) bool {
+	p := enc.opts.pseudonyms
+	return p != nil && matchKeyPath(p.keys, enc.redactPath+key)
+}
+
+// pseudonymizesPath reports whether values appended now, such as the
+// elements of an array, are inside a value whose key must be
+// pseudonymized.
+func (enc *ltsvEncoder) pseudonymizesPath() bool {
+	p := enc.opts.pseudonyms
+	return p != nil && enc.redactPath != "" && matchKeyPath(p.keys, enc.redactPath)
+}
+
+// appendPseudonym appends the pseudonym of val.
+func (enc *ltsvEncoder) appendPseudonym(val string) {
+	enc.appendString(enc.opts.pseudonyms.Pseudonym(val))
+	enc.pseudonymized = true
+}
+
+// addPseudonymKeyID writes the key ID label if the line contains
+// pseudonyms.
+func (enc *ltsvEncoder) addPseudonymKeyID() {
+	if !enc.pseudonymized || enc.opts.pseudonyms.KeyID == "" {
+		return
+	}
+	enc.AddString(enc.opts.pseudonymKeyIDKey, enc.opts.pseudonyms.KeyID)
+	enc.finishValue()
+}
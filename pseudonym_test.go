@@ -0,0 +1,166 @@
+package ltsv_test
+
+import (
+	"regexp"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPseudonymize(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	p := ltsv.Pseudonymizer{KeyID: "k1", Secret: []byte("secret")}
+	email := p.Pseudonym("jane@example.com")
+	ip := p.Pseudonym("192.0.2.1")
+	testCases := []struct {
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email", "*_ip")},
+			fields: []zapcore.Field{
+				zap.String("email", "jane@example.com"),
+				zap.ByteString("remote_ip", []byte("192.0.2.1")),
+				zap.String("user", "jane"),
+			},
+			want: "level:info\tmsg:hello\temail:" + email + "\tremote_ip:" + ip + "\tuser:jane\tpseudonymKeyID:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "req.email"), ltsv.PseudonymKeyIDKey("pkid")},
+			fields: []zapcore.Field{
+				zap.Dict("req", zap.String("email", "jane@example.com")),
+			},
+			want: "level:info\tmsg:hello\treq:{\"email\":\"" + email + "\"}\tpkid:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email"), ltsv.Flatten(".", 0)},
+			fields: []zapcore.Field{
+				zap.Namespace("req"),
+				zap.String("email", "jane@example.com"),
+			},
+			want: "level:info\tmsg:hello\treq.email:" + email + "\tpseudonymKeyID:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email"), ltsv.RedactKeys("email")},
+			fields: []zapcore.Field{
+				zap.String("email", "jane@example.com"),
+			},
+			want: "level:info\tmsg:hello\temail:[REDACTED]\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email")},
+			fields: []zapcore.Field{
+				zap.Strings("email", []string{"jane@example.com", "jane@example.com"}),
+			},
+			want: "level:info\tmsg:hello\temail:[\"" + email + "\",\"" + email + "\"]\tpseudonymKeyID:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email")},
+			fields: []zapcore.Field{
+				zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+					enc.AddString("name", "jane")
+					return enc.AddArray("email", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+						enc.AppendString("jane@example.com")
+						return nil
+					}))
+				})),
+			},
+			want: "level:info\tmsg:hello\tuser:{\"name\":\"jane\",\"email\":[\"" + email + "\"]}\tpseudonymKeyID:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email")},
+			fields: []zapcore.Field{
+				zap.Any("user", struct {
+					Name   string   `json:"name"`
+					Email  string   `json:"email"`
+					Emails []string `json:"emails"`
+					Age    int      `json:"age"`
+				}{Name: "jane", Email: "jane@example.com", Emails: []string{"jane@example.com"}, Age: 30}),
+			},
+			want: "level:info\tmsg:hello\tuser:{\"name\":\"jane\",\"email\":\"" + email + "\",\"emails\":[\"jane@example.com\"],\"age\":30}\tpseudonymKeyID:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email*")},
+			fields: []zapcore.Field{
+				zap.Reflect("emails", map[string]interface{}{"work": "jane@example.com", "count": 1}),
+			},
+			want: "level:info\tmsg:hello\temails:{\"count\":1,\"work\":\"" + email + "\"}\tpseudonymKeyID:k1\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(p, "email")},
+			fields: []zapcore.Field{
+				zap.String("user", "jane"),
+			},
+			want: "level:info\tmsg:hello\tuser:jane\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.Pseudonymize(ltsv.Pseudonymizer{KeyID: "k0"}, "email"), ltsv.Pseudonymize(p, "*_ip")},
+			fields: []zapcore.Field{
+				zap.String("email", "jane@example.com"),
+				zap.String("remote_ip", "192.0.2.1"),
+			},
+			want: "level:info\tmsg:hello\temail:" + email + "\tremote_ip:" + ip + "\tpseudonymKeyID:k1\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestPseudonymizer(t *testing.T) {
+	testCases := []struct {
+		p    ltsv.Pseudonymizer
+		raw  string
+		want *regexp.Regexp
+	}{
+		{
+			p:    ltsv.Pseudonymizer{Secret: []byte("secret")},
+			raw:  "jane@example.com",
+			want: regexp.MustCompile(`^[0-9a-f]{16}$`),
+		},
+		{
+			p:    ltsv.Pseudonymizer{Secret: []byte("secret"), Format: ltsv.Base64URLPseudonym, Length: -1},
+			raw:  "jane@example.com",
+			want: regexp.MustCompile(`^[-_0-9A-Za-z]{43}$`),
+		},
+		{
+			p:    ltsv.Pseudonymizer{Secret: []byte("secret"), Format: ltsv.TokenPseudonym},
+			raw:  "Jane.Doe-42@example.com",
+			want: regexp.MustCompile(`^[A-Z][a-z]{3}\.[A-Z][a-z]{2}-[0-9]{2}@[a-z]{7}\.[a-z]{3}$`),
+		},
+		{
+			p:    ltsv.Pseudonymizer{Secret: []byte("secret"), Format: ltsv.TokenPseudonym},
+			raw:  "0123456789012345678901234567890123456789",
+			want: regexp.MustCompile(`^[0-9]{40}$`),
+		},
+	}
+	for _, tc := range testCases {
+		got := tc.p.Pseudonym(tc.raw)
+		if !tc.want.MatchString(got) {
+			t.Errorf("got=%q, want match for %s, format=%s", got, tc.want, tc.p.Format)
+		}
+		if !tc.p.Verify(tc.raw, got) {
+			t.Errorf("Verify(%q, %q) = false, want true", tc.raw, got)
+		}
+		if tc.p.Verify(tc.raw+"x", got) {
+			t.Errorf("Verify(%q, %q) = true, want false", tc.raw+"x", got)
+		}
+		other := tc.p
+		other.Secret = []byte("other")
+		if other.Verify(tc.raw, got) {
+			t.Errorf("Verify with another secret = true, want false; raw=%q", tc.raw)
+		}
+	}
+}
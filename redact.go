@@ -0,0 +1,238 @@
+package ltsv
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+)
+
+// DefaultRedactionMask is the mask used for redacted values unless another
+// one is set with the RedactionMask option.
+const DefaultRedactionMask = "[REDACTED]"
+
+// redactor holds the redaction settings of an encoder.
+type redactor struct {
+	keys       []string
+	values     []*regexp.Regexp
+	mask       string
+	revealLast int
+}
+
+func (o *encoderOptions) redactor() *redactor {
+	if o.redaction == nil {
+		o.redaction = &redactor{mask: DefaultRedactionMask}
+	}
+	return o.redaction
+}
+
+// RedactKeys makes the encoder mask the values of keys matching any of the
+// patterns, which use the syntax of path.Match.
+//
+// A pattern containing a dot is matched against the path of the key, which
+// consists of the keys of the enclosing namespaces and objects and the key
+// itself joined with dots, e.g. "http.headers.authorization". Elements of
+// flattened arrays have their index in the path, while elements of arrays
+// written as JSON share the path of the array. Other patterns, such as
+// "password" or "*_token", are matched against each key in the path.
+//
+// Objects, arrays and reflected values with a matching key are replaced
+// as a whole. The namespace of a matching key stays, but the values of all
+// fields in it are masked.
+func RedactKeys(patterns ...string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		r := opts.redactor()
+		r.keys = append(r.keys, patterns...)
+	})
+}
+
+// RedactValues makes the encoder mask the parts of string values matching
+// any of the regular expressions, at any level and including the message.
+func RedactValues(patterns ...*regexp.Regexp) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		r := opts.redactor()
+		r.values = append(r.values, patterns...)
+	})
+}
+
+// RedactionMask sets the mask for redacted values. If revealLast is
+// positive, the last revealLast characters of redacted strings follow the
+// mask, e.g. "[REDACTED]1234". Strings which are not longer than twice
+// revealLast characters are masked entirely.
+func RedactionMask(mask string, revealLast int) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		r := opts.redactor()
+		r.mask = mask
+		r.revealLast = revealLast
+	})
+}
+
+// matchKey reports whether the key at p, a dot-separated path, or one of
+// its enclosing namespaces must be redacted.
+func (r *redactor) matchKey(p string) bool {
+	return matchKeyPath(r.keys, p)
+}
+
+// matchKeyPath reports whether the key at p, a dot-separated path, or one
+// of its enclosing namespaces matches any of the patterns.
+func matchKeyPath(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		dotted := strings.Contains(pattern, ".")
+		start := 0
+		for i := 0; i <= len(p); i++ {
+			if i < len(p) && p[i] != '.' {
+				continue
+			}
+			s := p[:i]
+			if !dotted {
+				s = p[start:i]
+			}
+			if ok, _ := path.Match(pattern, s); ok {
+				return true
+			}
+			start = i + 1
+		}
+	}
+	return false
+}
+
+// maskString returns the mask for the string s.
+func (r *redactor) maskString(s string) string {
+	if r.revealLast <= 0 || utf8.RuneCountInString(s) <= 2*r.revealLast {
+		return r.mask
+	}
+	i := len(s)
+	for n := 0; n < r.revealLast; n++ {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return r.mask + s[i:]
+}
+
+// redactString masks the parts of s matching the value patterns.
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.values {
+		s = re.ReplaceAllStringFunc(s, r.maskString)
+	}
+	return s
+}
+
+// redactsKey reports whether the value of key must be masked.
+func (enc *ltsvEncoder) redactsKey(key string) bool {
+	r := enc.opts.redaction
+	return r != nil && len(r.keys) > 0 && r.matchKey(enc.redactPath+key)
+}
+
+// pushRedactPath appends key to the path of enclosing keys and returns the
+// previous path.
+func (enc *ltsvEncoder) pushRedactPath(key string) string {
+	saved := enc.redactPath
+	if enc.opts.redaction != nil || enc.opts.pseudonyms != nil {
+		enc.redactPath = saved + key + "."
+	}
+	return saved
+}
+
+// A jsonFilter masks and pseudonymizes the values in a JSON value. Either
+// of its settings may be nil.
+type jsonFilter struct {
+	r  *redactor
+	ps *pseudonymizer
+
+	// pseudonymized is set once a value has been replaced with a
+	// pseudonym.
+	pseudonymized bool
+}
+
+// filterJSON writes data, a JSON value marshaled for a key at the path p,
+// to out with its values masked and pseudonymized, preserving the order of
+// object members. On error, out may hold part of the value.
+func (f *jsonFilter) filterJSON(out *buffer.Buffer, data []byte, p string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return f.filterJSONValue(dec, out, p)
+}
+
+func (f *jsonFilter) filterJSONValue(dec *json.Decoder, out *buffer.Buffer, p string) error {
+	r := f.r
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		close := byte(']')
+		if tok == '{' {
+			close = '}'
+		}
+		out.WriteByte(byte(tok))
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if close == ']' {
+				if err := f.filterJSONValue(dec, out, p); err != nil {
+					return err
+				}
+				continue
+			}
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := keyTok.(string)
+			writeJSONString(out, key)
+			out.WriteByte(':')
+			if r != nil && len(r.keys) > 0 && r.matchKey(p+key) {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					return err
+				}
+				var s string
+				if json.Unmarshal(raw, &s) == nil {
+					writeJSONString(out, r.maskString(s))
+				} else {
+					writeJSONString(out, r.mask)
+				}
+				continue
+			}
+			if err := f.filterJSONValue(dec, out, p+key+"."); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		out.WriteByte(close)
+	case string:
+		switch {
+		case f.ps != nil && matchKeyPath(f.ps.keys, p):
+			writeJSONString(out, f.ps.Pseudonym(tok))
+			f.pseudonymized = true
+		case r != nil:
+			writeJSONString(out, r.redactString(tok))
+		default:
+			writeJSONString(out, tok)
+		}
+	case json.Number:
+		out.WriteString(tok.String())
+	case bool:
+		if tok {
+			out.WriteString("true")
+		} else {
+			out.WriteString("false")
+		}
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+func writeJSONString(out *buffer.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	out.Write(b)
+}
@@ -0,0 +1,114 @@
+package ltsv_test
+
+import (
+	"regexp"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRedact(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	keys := ltsv.RedactKeys("password", "*_token", "http.headers.authorization")
+	card := ltsv.RedactValues(regexp.MustCompile(`\b\d{4}-\d{4}-\d{4}-\d{4}\b`))
+	testCases := []struct {
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			opts: []ltsv.Option{keys},
+			fields: []zapcore.Field{
+				zap.String("user", "jane"),
+				zap.String("password", "secret"),
+				zap.String("access_token", "abcdef"),
+				zap.Int("refresh_token", 1),
+			},
+			want: "level:info\tmsg:hello\tuser:jane\tpassword:[REDACTED]\taccess_token:[REDACTED]\trefresh_token:[REDACTED]\n",
+		},
+		{
+			opts: []ltsv.Option{keys},
+			fields: []zapcore.Field{
+				zap.Namespace("http"),
+				zap.Dict("headers", zap.String("authorization", "Bearer x"), zap.String("accept", "*/*")),
+				zap.Dict("user", zap.String("name", "jane"), zap.String("password", "secret")),
+			},
+			want: "level:info\tmsg:hello\thttp:{\"headers\":{\"authorization\":\"[REDACTED]\",\"accept\":\"*/*\"},\"user\":{\"name\":\"jane\",\"password\":\"[REDACTED]\"}}\n",
+		},
+		{
+			opts: []ltsv.Option{keys, ltsv.Flatten("_", 0)},
+			fields: []zapcore.Field{
+				zap.Namespace("http"),
+				zap.Dict("headers", zap.String("authorization", "Bearer x"), zap.String("accept", "*/*")),
+			},
+			want: "level:info\tmsg:hello\thttp_headers_authorization:[REDACTED]\thttp_headers_accept:*/*\n",
+		},
+		{
+			opts: []ltsv.Option{keys},
+			fields: []zapcore.Field{
+				zap.Any("login", map[string]interface{}{"user": "jane", "password": "secret", "id_token": 1}),
+				zap.Any("password", []string{"a", "b"}),
+			},
+			want: "level:info\tmsg:hello\tlogin:{\"id_token\":\"[REDACTED]\",\"password\":\"[REDACTED]\",\"user\":\"jane\"}\tpassword:[REDACTED]\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.RedactKeys("creds")},
+			fields: []zapcore.Field{
+				zap.Namespace("creds"),
+				zap.String("user", "jane"),
+				zap.Int("pin", 1234),
+			},
+			want: "level:info\tmsg:hello\tcreds:{\"user\":\"[REDACTED]\",\"pin\":\"[REDACTED]\"}\n",
+		},
+		{
+			opts: []ltsv.Option{card, ltsv.RedactionMask("****", 4)},
+			fields: []zapcore.Field{
+				zap.String("note", "paid with 1234-5678-9012-3456 today"),
+				zap.Strings("cards", []string{"1111-2222-3333-4444"}),
+				zap.Any("order", map[string]string{"card": "1111-2222-3333-4444"}),
+			},
+			want: "level:info\tmsg:hello\tnote:paid with ****3456 today\tcards:[\"****4444\"]\torder:{\"card\":\"****4444\"}\n",
+		},
+		{
+			opts: []ltsv.Option{ltsv.RedactKeys("token"), ltsv.RedactionMask("***", 2)},
+			fields: []zapcore.Field{
+				zap.String("token", "abcdefgh"),
+				zap.ByteString("token", []byte("abc")),
+			},
+			want: "level:info\tmsg:hello\ttoken:***gh\ttoken:***\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
+
+func TestRedactWith(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	enc := ltsv.NewLTSVEncoder(cfg, ltsv.RedactKeys("http.headers.authorization", "password")).Clone()
+	enc.AddString("password", "secret")
+	enc.OpenNamespace("http")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []zapcore.Field{
+		zap.Dict("headers", zap.String("authorization", "Bearer x")),
+	})
+	if err != nil {
+		t.Fatalf("failed to encode entry; err=%+v", err)
+	}
+	want := "level:info\tmsg:hello\tpassword:[REDACTED]\thttp:{\"headers\":{\"authorization\":\"[REDACTED]\"}}\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+}
@@ -0,0 +1,289 @@
+package ltsv
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ReflectStructs makes the encoder write a struct added with
+// AddReflected, such as a zap.Any or zap.Reflect field, as separate
+// top-level labels, one for each of its fields. The labels are prefixed
+// with the key of the field and separator, or not prefixed at all if the
+// key is empty.
+//
+// Fields are named and selected with the "ltsv" struct tag, which works
+// like the "json" tag:
+//
+//	Status int       `ltsv:"status"`           // written as "status"
+//	Size   int64     `ltsv:"size,omitempty"`   // omitted if zero
+//	Secret string    `ltsv:"-"`                // never written
+//	Req    Request   `ltsv:",inline"`          // fields of Req are written at this level
+//	Time   time.Time `ltsv:"time"`             // written with EncodeTime
+//
+// Fields without an "ltsv" tag are named by their "json" tag, or by the
+// Go field name. Unexported fields are ignored, and embedded structs are
+// inlined. Fields of basic types, time.Time, time.Duration and []byte are
+// written like the corresponding zap fields, and fields implementing
+// zapcore.ObjectMarshaler or zapcore.ArrayMarshaler are marshaled with
+// them. Other fields, such as nested structs, maps and slices, are written
+// as JSON.
+//
+// Inside nested JSON values, structs are written as JSON as usual.
+func ReflectStructs(separator string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.reflectStructs = true
+		opts.structSep = separator
+	})
+}
+
+// StructMarshaler returns an ObjectMarshaler which adds the fields of the
+// struct v, or of the struct v points to, to an ObjectEncoder as
+// described for ReflectStructs. It works with any encoder, e.g. with
+// zap.Inline to write the fields as top-level labels. A nil pointer adds
+// nothing, and any other value which is not a struct is an error.
+func StructMarshaler(v interface{}) zapcore.ObjectMarshaler {
+	return structMarshaler{reflect.ValueOf(v)}
+}
+
+type structMarshaler struct {
+	v reflect.Value
+}
+
+func (m structMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	v := m.v
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("ltsv: StructMarshaler of non-struct %s", v.Type())
+	}
+	for _, f := range cachedStructFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		if err := addValue(enc, f.name, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structField is the cached metadata of a struct field.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFieldCache maps a reflect.Type to its []structField.
+var structFieldCache sync.Map
+
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := structFieldCache.LoadOrStore(t, typeFields(t, nil))
+	return fields.([]structField)
+}
+
+// typeFields returns the fields of the struct type t, whose index in the
+// enclosing struct is prefixed with index.
+func typeFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("ltsv")
+		if !hasTag {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, opts = tag[:j], tag[j+1:]
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		inline := hasOption(opts, "inline") || (sf.Anonymous && name == "")
+		if inline && ft.Kind() == reflect.Struct {
+			if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+				// Pointers to unexported structs cannot be followed.
+				continue
+			}
+			fields = append(fields, typeFields(ft, fieldIndex)...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, structField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: hasOption(opts, "omitempty"),
+		})
+	}
+	return fields
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		if i := strings.IndexByte(opts, ','); i >= 0 {
+			opt, opts = opts[:i], opts[i+1:]
+		} else {
+			opt, opts = opts, ""
+		}
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but reports false
+// instead of panicking at a nil pointer to an inlined struct.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	objectMarshalerType = reflect.TypeOf((*zapcore.ObjectMarshaler)(nil)).Elem()
+	arrayMarshalerType  = reflect.TypeOf((*zapcore.ArrayMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// addValue adds v to enc under key according to its type.
+func addValue(enc zapcore.ObjectEncoder, key string, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return enc.AddReflected(key, nil)
+		}
+		if !v.Type().Implements(objectMarshalerType) && !v.Type().Implements(arrayMarshalerType) {
+			v = v.Elem()
+		}
+	}
+	if v.CanInterface() {
+		switch m := v.Interface().(type) {
+		case zapcore.ObjectMarshaler:
+			return enc.AddObject(key, m)
+		case zapcore.ArrayMarshaler:
+			return enc.AddArray(key, m)
+		}
+	}
+	switch v.Type() {
+	case timeType:
+		enc.AddTime(key, v.Interface().(time.Time))
+		return nil
+	case durationType:
+		enc.AddDuration(key, time.Duration(v.Int()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		enc.AddBool(key, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		enc.AddInt64(key, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		enc.AddUint64(key, v.Uint())
+	case reflect.Float32:
+		enc.AddFloat32(key, float32(v.Float()))
+	case reflect.Float64:
+		enc.AddFloat64(key, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		enc.AddComplex128(key, v.Complex())
+	case reflect.String:
+		enc.AddString(key, v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			enc.AddBinary(key, v.Bytes())
+			return nil
+		}
+		return addReflectedJSON(enc, key, v.Interface())
+	default:
+		return addReflectedJSON(enc, key, v.Interface())
+	}
+	return nil
+}
+
+// addReflectedJSON adds obj to enc as JSON, even if the LTSV encoder
+// writes structs as separate labels.
+func addReflectedJSON(enc zapcore.ObjectEncoder, key string, obj interface{}) error {
+	if e, ok := enc.(*ltsvEncoder); ok {
+		return e.addReflectedJSON(key, obj)
+	}
+	return enc.AddReflected(key, obj)
+}
+
+// reflectsStruct reports whether obj must be written as separate labels.
+func (enc *ltsvEncoder) reflectsStruct(obj interface{}) bool {
+	if !enc.opts.reflectStructs || enc.nestedLevel > 0 || enc.openNamespaces > 0 {
+		return false
+	}
+	t := reflect.TypeOf(obj)
+	if t != nil && t.Kind() == reflect.Ptr {
+		// A nil pointer is written as JSON null like without the option.
+		if reflect.ValueOf(obj).IsNil() {
+			return false
+		}
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	// Types with their own JSON form are written as such.
+	return !t.Implements(jsonMarshalerType) && !reflect.PtrTo(t).Implements(jsonMarshalerType)
+}
+
+// addStructLabels writes the fields of the struct obj as separate labels
+// prefixed with key.
+func (enc *ltsvEncoder) addStructLabels(key string, obj interface{}) error {
+	if key == "" {
+		return StructMarshaler(obj).MarshalLogObject(enc)
+	}
+	saved := enc.flatPrefix
+	savedPath := enc.pushRedactPath(key)
+	enc.flatPrefix = saved + key + enc.opts.structSep
+	err := StructMarshaler(obj).MarshalLogObject(enc)
+	enc.flatPrefix = saved
+	enc.redactPath = savedPath
+	return err
+}
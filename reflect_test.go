@@ -0,0 +1,102 @@
+package ltsv_test
+
+import (
+	"testing"
+	"time"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type accessRequest struct {
+	Method string `ltsv:"method"`
+	URI    string `ltsv:"uri"`
+}
+
+type accessRecord struct {
+	accessRequest
+	Status  int               `ltsv:"status"`
+	Size    int64             `ltsv:"size,omitempty"`
+	Reqtime time.Duration     `ltsv:"reqtime"`
+	Time    time.Time         `ltsv:"time"`
+	Secret  string            `ltsv:"-"`
+	Body    []byte            `ltsv:"body,omitempty"`
+	Headers map[string]string `ltsv:"headers,omitempty"`
+	User    *user             `ltsv:"user,omitempty"`
+	Vhost   string            `json:"vhost"`
+	Ratio   float64
+	private int
+}
+
+func TestReflectStructs(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	rec := accessRecord{
+		accessRequest: accessRequest{Method: "GET", URI: "/"},
+		Status:        200,
+		Reqtime:       1500 * time.Millisecond,
+		Time:          time.Date(2017, 5, 3, 21, 9, 11, 0, time.UTC),
+		Secret:        "s",
+		Headers:       map[string]string{"accept": "*/*"},
+		Vhost:         "example.com",
+		Ratio:         0.5,
+		private:       1,
+	}
+	labels := "method:GET\turi:/\tstatus:200\treqtime:1.5s\ttime:2017-05-03T21:09:11.000Z\theaders:{\"accept\":\"*/*\"}\tvhost:example.com\tRatio:0.5"
+	testCases := []struct {
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			opts:   []ltsv.Option{ltsv.ReflectStructs(".")},
+			fields: []zapcore.Field{zap.Any("", rec)},
+			want:   "level:info\tmsg:hello\t" + labels + "\n",
+		},
+		{
+			fields: []zapcore.Field{zap.Inline(ltsv.StructMarshaler(&rec))},
+			want:   "level:info\tmsg:hello\t" + labels + "\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.ReflectStructs("_")},
+			fields: []zapcore.Field{zap.Reflect("req", &accessRequest{Method: "GET", URI: "/"})},
+			want:   "level:info\tmsg:hello\treq_method:GET\treq_uri:/\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.ReflectStructs(".")},
+			fields: []zapcore.Field{zap.Any("rec", accessRecord{Status: 404, User: &jane, Body: []byte("hi")})},
+			want:   "level:info\tmsg:hello\trec.method:\trec.uri:\trec.status:404\trec.reqtime:0s\trec.time:0001-01-01T00:00:00.000Z\trec.body:aGk=\trec.user:{\"name\":\"Jane Doe\",\"email\":\"jane@test.com\",\"created_at\":315576000000000000}\trec.vhost:\trec.Ratio:0\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.ReflectStructs(".")},
+			fields: []zapcore.Field{zap.Dict("m", zap.Reflect("req", accessRequest{Method: "GET"})), zap.Reflect("t", time.Unix(0, 0).UTC())},
+			want:   "level:info\tmsg:hello\tm:{\"req\":{\"Method\":\"GET\",\"URI\":\"\"}}\tt:\"1970-01-01T00:00:00Z\"\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.ReflectStructs("."), ltsv.RedactKeys("req.uri")},
+			fields: []zapcore.Field{zap.Reflect("req", accessRequest{Method: "GET", URI: "/?token=x"})},
+			want:   "level:info\tmsg:hello\treq.method:GET\treq.uri:[REDACTED]\n",
+		},
+		{
+			opts:   []ltsv.Option{ltsv.ReflectStructs(".")},
+			fields: []zapcore.Field{zap.Reflect("req", (*accessRequest)(nil))},
+			want:   "level:info\tmsg:hello\treq:null\n",
+		},
+		{
+			fields: []zapcore.Field{zap.Object("n", ltsv.StructMarshaler(1))},
+			want:   "level:info\tmsg:hello\tn:{}\tnError:ltsv: StructMarshaler of non-struct int\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ltsv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ReflectedEncoder sets the constructor of the encoder for reflected
+// values, overriding NewReflectedEncoder of the EncoderConfig. The encoder
+// must write a single JSON value for each call to Encode, optionally
+// followed by newlines, such as NewFmtReflectedEncoder does, or a faster
+// JSON library.
+//
+// Without this option and NewReflectedEncoder, reflected values are
+// written with encoding/json in the same way as json.Marshal does.
+func ReflectedEncoder(newEncoder func(io.Writer) zapcore.ReflectedEncoder) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.newReflectedEncoder = newEncoder
+	})
+}
+
+// NewFmtReflectedEncoder returns a ReflectedEncoder which writes values
+// formatted with the %+v verb of the fmt package as JSON strings, e.g.
+// "{Name:jane Age:30}".
+func NewFmtReflectedEncoder(w io.Writer) zapcore.ReflectedEncoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &fmtReflectedEncoder{enc: enc}
+}
+
+type fmtReflectedEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *fmtReflectedEncoder) Encode(obj interface{}) error {
+	return e.enc.Encode(fmt.Sprintf("%+v", obj))
+}
+
+// bufWriter writes to the current buffer of an encoder, so that the
+// reflected encoder can stay with the encoder while buffers change.
+type bufWriter struct {
+	enc *ltsvEncoder
+}
+
+func (w bufWriter) Write(p []byte) (int, error) {
+	if w.enc.reflectDst != nil {
+		return w.enc.reflectDst.Write(p)
+	}
+	return w.enc.buf.Write(p)
+}
+
+// reflectedEncoder returns the encoder for reflected values. It is
+// created once and reused until the encoder is put back into the pool.
+func (enc *ltsvEncoder) reflectedEncoder() zapcore.ReflectedEncoder {
+	if enc.reflectEnc != nil && enc.reflectCfg == enc.EncoderConfig && enc.reflectOpts == enc.opts {
+		return enc.reflectEnc
+	}
+	newEncoder := enc.opts.newReflectedEncoder
+	if newEncoder == nil {
+		newEncoder = enc.NewReflectedEncoder
+	}
+	if newEncoder == nil {
+		newEncoder = defaultReflectedEncoder
+	}
+	enc.reflectEnc = newEncoder(bufWriter{enc})
+	enc.reflectCfg = enc.EncoderConfig
+	enc.reflectOpts = enc.opts
+	return enc.reflectEnc
+}
+
+func defaultReflectedEncoder(w io.Writer) zapcore.ReflectedEncoder {
+	return json.NewEncoder(w)
+}
+
+// encodeReflected writes obj with the reflected encoder, redacting it with
+// the path p. On error, nothing is written.
+func (enc *ltsvEncoder) encodeReflected(obj interface{}, p string) error {
+	if enc.opts.redaction == nil && enc.opts.pseudonyms == nil {
+		start := enc.buf.Len()
+		if err := enc.reflectedEncoder().Encode(obj); err != nil {
+			enc.truncateBuf(start)
+			return err
+		}
+		trimNewlines(enc.buf, start)
+		enc.valueJSON = true
+		return nil
+	}
+
+	// Encode to a scratch buffer, so that the filtered value is appended
+	// to the line without rewriting it.
+	scratch := bufferpool.Get()
+	defer scratch.Free()
+	enc.reflectDst = scratch
+	err := enc.reflectedEncoder().Encode(obj)
+	enc.reflectDst = nil
+	if err != nil {
+		return err
+	}
+	trimNewlines(scratch, 0)
+	start := enc.buf.Len()
+	f := jsonFilter{r: enc.opts.redaction, ps: enc.opts.pseudonyms}
+	if err := f.filterJSON(enc.buf, scratch.Bytes(), p); err != nil {
+		enc.truncateBuf(start)
+		return err
+	}
+	enc.pseudonymized = enc.pseudonymized || f.pseudonymized
+	enc.valueJSON = true
+	return nil
+}
+
+// trimNewlines removes the newlines which encoders like json.Encoder write
+// after each value from the end of buf, but not before start.
+func trimNewlines(buf *buffer.Buffer, start int) {
+	for buf.Len() > start && buf.Bytes()[buf.Len()-1] == '\n' {
+		buf.TrimNewline()
+	}
+}
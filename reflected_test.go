@@ -0,0 +1,79 @@
+package ltsv_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type indentReflectedEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *indentReflectedEncoder) Encode(obj interface{}) error {
+	return e.enc.Encode(map[string]interface{}{"wrapped": obj})
+}
+
+func TestReflectedEncoder(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	customCfg := cfg
+	customCfg.NewReflectedEncoder = func(w io.Writer) zapcore.ReflectedEncoder {
+		return &indentReflectedEncoder{enc: json.NewEncoder(w)}
+	}
+	type point struct{ X, Y int }
+	testCases := []struct {
+		cfg    zapcore.EncoderConfig
+		opts   []ltsv.Option
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			cfg:    cfg,
+			fields: []zapcore.Field{zap.Reflect("p", point{1, 2}), zap.Reflect("s", "<a>")},
+			want:   "level:info\tmsg:hello\tp:{\"X\":1,\"Y\":2}\ts:\"\\u003ca\\u003e\"\n",
+		},
+		{
+			cfg:    customCfg,
+			fields: []zapcore.Field{zap.Reflect("p", point{1, 2})},
+			want:   "level:info\tmsg:hello\tp:{\"wrapped\":{\"X\":1,\"Y\":2}}\n",
+		},
+		{
+			cfg:    customCfg,
+			opts:   []ltsv.Option{ltsv.ReflectedEncoder(ltsv.NewFmtReflectedEncoder)},
+			fields: []zapcore.Field{zap.Reflect("p", point{1, 2}), zap.Dict("m", zap.Reflect("p", &point{3, 4}))},
+			want:   "level:info\tmsg:hello\tp:\"{X:1 Y:2}\"\tm:{\"p\":\"&{X:3 Y:4}\"}\n",
+		},
+		{
+			cfg:    cfg,
+			fields: []zapcore.Field{zap.Reflect("ch", make(chan int)), zap.Int("n", 1)},
+			want:   "level:info\tmsg:hello\tchError:json: unsupported type: chan int\tn:1\n",
+		},
+		{
+			cfg:    cfg,
+			fields: []zapcore.Field{zap.Dict("m", zap.Int("a", 1), zap.Reflect("ch", make(chan int)))},
+			want:   "level:info\tmsg:hello\tm:{\"a\":1,\"chError\":\"json: unsupported type: chan int\"}\n",
+		},
+		{
+			cfg:    cfg,
+			opts:   []ltsv.Option{ltsv.MaxValueBytes(30)},
+			fields: []zapcore.Field{zap.String("a", "x"), zap.Reflect("ch", make(chan int))},
+			want:   "level:info\tmsg:hello\ta:x\tchError:json: u…(truncated 25 bytes)\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(tc.cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, tc.fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; fields=%+v, err=%+v", tc.fields, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q, fields=%+v", got, tc.want, tc.fields)
+		}
+	}
+}
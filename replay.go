@@ -0,0 +1,384 @@
+package ltsv
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// A TimeDecoder parses a time written by a zapcore.TimeEncoder.
+type TimeDecoder func(string) (time.Time, error)
+
+// A LevelDecoder parses a level written by a zapcore.LevelEncoder.
+type LevelDecoder func(string) (zapcore.Level, error)
+
+// A DurationDecoder parses a duration written by a zapcore.DurationEncoder.
+type DurationDecoder func(string) (time.Duration, error)
+
+// EpochTimeDecoder parses a floating-point number of seconds since the Unix
+// epoch, as written by zapcore.EpochTimeEncoder.
+func EpochTimeDecoder(s string) (time.Time, error) {
+	nanos, err := parseDecimal(s, 9)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// EpochMillisTimeDecoder parses a floating-point number of milliseconds since
+// the Unix epoch, as written by zapcore.EpochMillisTimeEncoder.
+func EpochMillisTimeDecoder(s string) (time.Time, error) {
+	nanos, err := parseDecimal(s, 6)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// EpochNanosTimeDecoder parses an integer number of nanoseconds since the
+// Unix epoch, as written by zapcore.EpochNanosTimeEncoder.
+func EpochNanosTimeDecoder(s string) (time.Time, error) {
+	nanos, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// ISO8601TimeDecoder parses a time written by zapcore.ISO8601TimeEncoder.
+func ISO8601TimeDecoder(s string) (time.Time, error) {
+	return time.Parse("2006-01-02T15:04:05.000Z0700", s)
+}
+
+// RFC3339TimeDecoder parses a time written by zapcore.RFC3339TimeEncoder or
+// zapcore.RFC3339NanoTimeEncoder.
+func RFC3339TimeDecoder(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// CLFTimeDecoder parses a time written by CLFTimeEncoder.
+func CLFTimeDecoder(s string) (time.Time, error) {
+	return time.Parse(clfLayout, s)
+}
+
+// UnixMilliTimeDecoder parses an integer number of milliseconds since the
+// Unix epoch, as written by UnixMilliTimeEncoder.
+func UnixMilliTimeDecoder(s string) (time.Time, error) {
+	millis, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, millis*int64(time.Millisecond)), nil
+}
+
+// UnixMicroTimeDecoder parses an integer number of microseconds since the
+// Unix epoch, as written by UnixMicroTimeEncoder.
+func UnixMicroTimeDecoder(s string) (time.Time, error) {
+	micros, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, micros*int64(time.Microsecond)), nil
+}
+
+// TextLevelDecoder parses a level written by zapcore.LowercaseLevelEncoder
+// or zapcore.CapitalLevelEncoder.
+func TextLevelDecoder(s string) (zapcore.Level, error) {
+	var l zapcore.Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
+
+// SyslogSeverityLevelDecoder parses a syslog severity, as written by
+// SyslogSeverityLevelEncoder.
+func SyslogSeverityLevelDecoder(s string) (zapcore.Level, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	for l := zapcore.FatalLevel; l >= zapcore.DebugLevel; l-- {
+		if SyslogSeverity(l) == n {
+			return l, nil
+		}
+	}
+	if n == 5 {
+		// Notice is between info and warning.
+		return zapcore.InfoLevel, nil
+	}
+	return 0, fmt.Errorf("unknown syslog severity %d", n)
+}
+
+// ShortLevelDecoder parses a level written by ShortLevelEncoder.
+func ShortLevelDecoder(s string) (zapcore.Level, error) {
+	for l := zapcore.DebugLevel; l <= zapcore.FatalLevel; l++ {
+		if shortLevel(l) == s {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown short level %q", s)
+}
+
+// SecondsDurationDecoder parses a floating-point number of seconds, as
+// written by zapcore.SecondsDurationEncoder.
+func SecondsDurationDecoder(s string) (time.Duration, error) {
+	nanos, err := parseDecimal(s, 9)
+	return time.Duration(nanos), err
+}
+
+// MillisDurationDecoder parses a floating-point number of milliseconds, as
+// written by zapcore.MillisDurationEncoder.
+func MillisDurationDecoder(s string) (time.Duration, error) {
+	nanos, err := parseDecimal(s, 6)
+	return time.Duration(nanos), err
+}
+
+// MicrosDurationDecoder parses an integer number of microseconds, as
+// written by MicrosDurationEncoder.
+func MicrosDurationDecoder(s string) (time.Duration, error) {
+	micros, err := strconv.ParseInt(s, 10, 64)
+	return time.Duration(micros) * time.Microsecond, err
+}
+
+// NanosDurationDecoder parses an integer number of nanoseconds, as written
+// by zapcore.NanosDurationEncoder.
+func NanosDurationDecoder(s string) (time.Duration, error) {
+	nanos, err := strconv.ParseInt(s, 10, 64)
+	return time.Duration(nanos), err
+}
+
+// StringDurationDecoder parses a duration written by
+// zapcore.StringDurationEncoder.
+func StringDurationDecoder(s string) (time.Duration, error) {
+	return time.ParseDuration(s)
+}
+
+// parseDecimal parses a decimal number and returns it multiplied by
+// 10^scale. Numbers with at most scale fractional digits are converted
+// exactly.
+func parseDecimal(s string, scale int) (int64, error) {
+	pow := int64(1)
+	for i := 0; i < scale; i++ {
+		pow *= 10
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if len(fracPart) <= scale {
+		n, err1 := strconv.ParseInt(intPart, 10, 64)
+		f, err2 := strconv.ParseUint(fracPart+strings.Repeat("0", scale-len(fracPart)), 10, 64)
+		if err1 == nil && err2 == nil && n <= math.MaxInt64/pow && n >= math.MinInt64/pow {
+			if strings.HasPrefix(intPart, "-") {
+				return n*pow - int64(f), nil
+			}
+			return n*pow + int64(f), nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(f * float64(pow)), nil
+}
+
+// A ReplayConfig describes how to turn records back into entries and
+// fields. The keys in EncoderConfig select the labels holding the entry
+// metadata; its encoder funcs are not used.
+//
+// Nil decoders default to those of NewProductionReplayConfig, so the zero
+// value turns all labels into fields.
+type ReplayConfig struct {
+	EncoderConfig zapcore.EncoderConfig
+
+	DecodeTime     TimeDecoder
+	DecodeLevel    LevelDecoder
+	DecodeDuration DurationDecoder
+
+	// TimeKeys and DurationKeys list the labels of fields to be decoded
+	// as times and durations with DecodeTime and DecodeDuration.
+	TimeKeys     []string
+	DurationKeys []string
+
+	// SeverityKey is the label of the severity written with the
+	// SeverityKey option. It is not turned into a field, since the level
+	// is decoded from the level label.
+	SeverityKey string
+
+	// InferTypes makes other labels whose values look like integers,
+	// floating-point numbers or booleans become fields of that type.
+	// Otherwise they become string fields. JSON objects and arrays are
+	// always passed on as raw JSON.
+	InferTypes bool
+}
+
+// NewProductionReplayConfig returns a ReplayConfig for logs written with
+// NewProductionEncoderConfig.
+func NewProductionReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		EncoderConfig:  NewProductionEncoderConfig(),
+		DecodeTime:     EpochTimeDecoder,
+		DecodeLevel:    TextLevelDecoder,
+		DecodeDuration: SecondsDurationDecoder,
+	}
+}
+
+// NewDevelopmentReplayConfig returns a ReplayConfig for logs written with
+// NewDevelopmentEncoderConfig.
+func NewDevelopmentReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		EncoderConfig:  NewDevelopmentEncoderConfig(),
+		DecodeTime:     ISO8601TimeDecoder,
+		DecodeLevel:    TextLevelDecoder,
+		DecodeDuration: StringDurationDecoder,
+	}
+}
+
+// NewApacheReplayConfig returns a ReplayConfig for logs written with
+// NewApacheEncoderConfig.
+func NewApacheReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		EncoderConfig:  NewApacheEncoderConfig(),
+		DecodeTime:     CLFTimeDecoder,
+		DecodeLevel:    TextLevelDecoder,
+		DecodeDuration: MicrosDurationDecoder,
+	}
+}
+
+// NewNginxReplayConfig returns a ReplayConfig for logs written with
+// NewNginxEncoderConfig. Times are parsed with RFC3339TimeDecoder, which
+// also accepts the times written by RFC3339TimeEncoder, and durations
+// with SecondsDurationDecoder.
+func NewNginxReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		EncoderConfig:  NewNginxEncoderConfig(),
+		DecodeTime:     RFC3339TimeDecoder,
+		DecodeLevel:    TextLevelDecoder,
+		DecodeDuration: SecondsDurationDecoder,
+	}
+}
+
+// Entry reconstructs the entry and the fields from a record.
+func (c ReplayConfig) Entry(rec Record) (zapcore.Entry, []zapcore.Field, error) {
+	c = c.withDefaults()
+	var ent zapcore.Entry
+	fields := make([]zapcore.Field, 0, len(rec))
+	for _, p := range rec {
+		var err error
+		switch key := p.Label; {
+		case key == "":
+			fields = append(fields, zap.String(key, p.Value))
+		case key == c.EncoderConfig.TimeKey:
+			ent.Time, err = c.DecodeTime(p.Value)
+		case key == c.SeverityKey:
+			continue
+		case key == c.EncoderConfig.LevelKey:
+			ent.Level, err = c.DecodeLevel(p.Value)
+		case key == c.EncoderConfig.NameKey:
+			ent.LoggerName = p.Value
+		case key == c.EncoderConfig.CallerKey:
+			fn := ent.Caller.Function
+			ent.Caller, err = parseCaller(p.Value)
+			ent.Caller.Function = fn
+		case key == c.EncoderConfig.FunctionKey:
+			ent.Caller.Function = p.Value
+		case key == c.EncoderConfig.MessageKey:
+			ent.Message = p.Value
+		case key == c.EncoderConfig.StacktraceKey:
+			ent.Stack = p.Value
+		default:
+			var f zapcore.Field
+			f, err = c.field(p)
+			fields = append(fields, f)
+		}
+		if err != nil {
+			return zapcore.Entry{}, nil, fmt.Errorf("ltsv: label %q: %v", p.Label, err)
+		}
+	}
+	return ent, fields, nil
+}
+
+// withDefaults returns c with the nil decoders set to the defaults.
+func (c ReplayConfig) withDefaults() ReplayConfig {
+	if c.DecodeTime == nil {
+		c.DecodeTime = EpochTimeDecoder
+	}
+	if c.DecodeLevel == nil {
+		c.DecodeLevel = TextLevelDecoder
+	}
+	if c.DecodeDuration == nil {
+		c.DecodeDuration = SecondsDurationDecoder
+	}
+	return c
+}
+
+func (c ReplayConfig) field(p Pair) (zapcore.Field, error) {
+	if containsString(c.TimeKeys, p.Label) {
+		t, err := c.DecodeTime(p.Value)
+		return zap.Time(p.Label, t), err
+	}
+	if containsString(c.DurationKeys, p.Label) {
+		d, err := c.DecodeDuration(p.Value)
+		return zap.Duration(p.Label, d), err
+	}
+	if isJSONValue([]byte(p.Value)) {
+		return zap.Reflect(p.Label, json.RawMessage(p.Value)), nil
+	}
+	if c.InferTypes {
+		if i, err := strconv.ParseInt(p.Value, 10, 64); err == nil {
+			return zap.Int64(p.Label, i), nil
+		}
+		if f, err := strconv.ParseFloat(p.Value, 64); err == nil {
+			return zap.Float64(p.Label, f), nil
+		}
+		if b, err := strconv.ParseBool(p.Value); err == nil && (p.Value == "true" || p.Value == "false") {
+			return zap.Bool(p.Label, b), nil
+		}
+	}
+	return zap.String(p.Label, p.Value), nil
+}
+
+// parseCaller parses a caller written as "file:line".
+func parseCaller(s string) (zapcore.EntryCaller, error) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return zapcore.EntryCaller{}, fmt.Errorf("invalid caller %q", s)
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return zapcore.EntryCaller{}, fmt.Errorf("invalid caller %q", s)
+	}
+	return zapcore.EntryCaller{Defined: true, File: s[:i], Line: line}, nil
+}
+
+func containsString(a []string, s string) bool {
+	for _, e := range a {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay writes the entries read by dec to core, skipping those whose
+// level is not enabled by core.
+func Replay(dec *Decoder, core zapcore.Core, cfg ReplayConfig) error {
+	for dec.Scan() {
+		ent, fields, err := cfg.Entry(dec.Record())
+		if err != nil {
+			return fmt.Errorf("%v (line %d)", err, dec.Line())
+		}
+		if !core.Enabled(ent.Level) {
+			continue
+		}
+		if err := core.Write(ent, fields); err != nil {
+			return err
+		}
+	}
+	return dec.Err()
+}
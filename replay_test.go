@@ -0,0 +1,112 @@
+package ltsv_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestReplay(t *testing.T) {
+	testCases := []struct {
+		encCfg    zapcore.EncoderConfig
+		replayCfg ltsv.ReplayConfig
+	}{
+		{
+			encCfg:    ltsv.NewProductionEncoderConfig(),
+			replayCfg: ltsv.NewProductionReplayConfig(),
+		},
+		{
+			encCfg:    ltsv.NewDevelopmentEncoderConfig(),
+			replayCfg: ltsv.NewDevelopmentReplayConfig(),
+		},
+		{
+			encCfg:    ltsv.NewApacheEncoderConfig(),
+			replayCfg: ltsv.NewApacheReplayConfig(),
+		},
+		{
+			encCfg:    ltsv.NewNginxEncoderConfig(),
+			replayCfg: ltsv.NewNginxReplayConfig(),
+		},
+	}
+	for _, tc := range testCases {
+		ent := zapcore.Entry{
+			Level:      zapcore.WarnLevel,
+			Time:       time.Date(2017, 5, 3, 21, 9, 11, 983000000, time.UTC),
+			LoggerName: "main",
+			Caller:     zapcore.EntryCaller{Defined: true, File: "pkg/main.go", Line: 42},
+			Message:    "hello",
+			Stack:      "main.main\n\tmain.go:42",
+		}
+		fields := []zapcore.Field{
+			zap.Duration("latency", 1500*time.Millisecond),
+			zap.Int("count", 3),
+			zap.Bool("ok", true),
+			zap.Ints("ids", []int{1, 2}),
+			zap.String("s", "a\tb"),
+		}
+		buf, err := ltsv.NewLTSVEncoder(tc.encCfg).EncodeEntry(ent, fields)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		want := buf.String()
+
+		var out bytes.Buffer
+		core := zapcore.NewCore(ltsv.NewLTSVEncoder(tc.encCfg), zapcore.AddSync(&out), zapcore.DebugLevel)
+		cfg := tc.replayCfg
+		cfg.DurationKeys = []string{"latency"}
+		cfg.InferTypes = true
+		if err := ltsv.Replay(ltsv.NewDecoder(strings.NewReader(want)), core, cfg); err != nil {
+			t.Fatalf("failed to replay; err=%+v", err)
+		}
+		if got := out.String(); got != want {
+			t.Errorf("got=%q, want=%q", got, want)
+		}
+	}
+}
+
+func TestReplayConfigEntryError(t *testing.T) {
+	rec, err := ltsv.ParseRecord([]byte("time:yesterday\tmsg:hello"), ltsv.JSONEscape)
+	if err != nil {
+		t.Fatalf("failed to parse record; err=%+v", err)
+	}
+	_, _, err = ltsv.NewProductionReplayConfig().Entry(rec)
+	if err == nil || !strings.Contains(err.Error(), `label "time"`) {
+		t.Errorf("got=%v, want error for time label", err)
+	}
+}
+
+func TestReplayConfigNilDecoders(t *testing.T) {
+	rec, err := ltsv.ParseRecord([]byte("time:1493845751.983\tlevel:warn\tmsg:hello\tlatency:1.5"), ltsv.JSONEscape)
+	if err != nil {
+		t.Fatalf("failed to parse record; err=%+v", err)
+	}
+
+	var zero ltsv.ReplayConfig
+	ent, fields, err := zero.Entry(rec)
+	if err != nil {
+		t.Fatalf("failed to replay with zero config; err=%+v", err)
+	}
+	if ent.Message != "" || len(fields) != len(rec) {
+		t.Errorf("got entry=%+v, fields=%+v, want all labels as fields", ent, fields)
+	}
+
+	cfg := ltsv.ReplayConfig{
+		EncoderConfig: ltsv.NewProductionEncoderConfig(),
+		DurationKeys:  []string{"latency"},
+	}
+	ent, fields, err = cfg.Entry(rec)
+	if err != nil {
+		t.Fatalf("failed to replay with partial config; err=%+v", err)
+	}
+	if ent.Level != zapcore.WarnLevel || ent.Message != "hello" || ent.Time.Unix() != 1493845751 {
+		t.Errorf("got entry=%+v", ent)
+	}
+	if len(fields) != 1 || fields[0].Type != zapcore.DurationType || time.Duration(fields[0].Integer) != 1500*time.Millisecond {
+		t.Errorf("got fields=%+v, want latency duration", fields)
+	}
+}
@@ -0,0 +1,239 @@
+package ltsv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultStacktraceSeparator is the separator of frames used by
+// StacktraceJoined unless another one is set with the StacktraceSeparator
+// option.
+const DefaultStacktraceSeparator = " | "
+
+// A StacktraceMode selects how the encoder writes the stacktrace of an
+// entry.
+type StacktraceMode int
+
+const (
+	// StacktraceString writes the stacktrace as a single escaped value.
+	// This is the default mode.
+	StacktraceString StacktraceMode = iota
+	// StacktraceJoined writes the frames, each formatted as
+	// "function file:line", joined with the stacktrace separator.
+	StacktraceJoined
+	// StacktraceLabels writes each frame as "function file:line" under a
+	// label of its own, the stacktrace key followed by a dot and the index
+	// of the frame, e.g. "stacktrace.0".
+	StacktraceLabels
+	// StacktraceJSON writes the frames as a JSON array of objects with the
+	// keys "func", "file" and "line".
+	StacktraceJSON
+)
+
+// String returns a lower-case ASCII representation of the mode.
+func (m StacktraceMode) String() string {
+	switch m {
+	case StacktraceString:
+		return "string"
+	case StacktraceJoined:
+		return "joined"
+	case StacktraceLabels:
+		return "labels"
+	case StacktraceJSON:
+		return "json"
+	default:
+		return fmt.Sprintf("StacktraceMode(%d)", int(m))
+	}
+}
+
+// MarshalText marshals the mode to text. See String.
+func (m StacktraceMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText unmarshals text to a mode. Valid values are "string",
+// "joined", "labels" and "json".
+func (m *StacktraceMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "string", "":
+		*m = StacktraceString
+	case "joined":
+		*m = StacktraceJoined
+	case "labels":
+		*m = StacktraceLabels
+	case "json":
+		*m = StacktraceJSON
+	default:
+		return fmt.Errorf("ltsv: unknown stacktrace mode %q", text)
+	}
+	return nil
+}
+
+// DefaultStacktraceFilter lists the function prefixes of the frames of
+// the Go runtime and zap itself, for StacktraceFilter.
+var DefaultStacktraceFilter = []string{"runtime.", "go.uber.org/zap."}
+
+// Stacktrace sets the mode for writing stacktraces.
+func Stacktrace(mode StacktraceMode) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.stackMode = mode
+	})
+}
+
+// StacktraceSeparator sets the separator of frames for StacktraceJoined.
+func StacktraceSeparator(sep string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.stackSep = sep
+	})
+}
+
+// StacktraceMaxFrames limits the number of frames written to n, after
+// filtering. A limit of zero or less means no limit.
+func StacktraceMaxFrames(n int) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.stackMaxFrames = n
+	})
+}
+
+// StacktraceFilter drops the frames whose function name starts with any
+// of the prefixes, such as DefaultStacktraceFilter.
+func StacktraceFilter(prefixes ...string) Option {
+	return optionFunc(func(opts *encoderOptions) {
+		opts.stackFilter = append([]string(nil), prefixes...)
+	})
+}
+
+// A stackFrame is a frame of a stacktrace as written by zap.
+type stackFrame struct {
+	Func string
+	File string
+	Line int
+}
+
+func (f stackFrame) String() string {
+	return f.Func + " " + f.File + ":" + strconv.Itoa(f.Line)
+}
+
+func (f stackFrame) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("func", f.Func)
+	enc.AddString("file", f.File)
+	enc.AddInt("line", f.Line)
+	return nil
+}
+
+type stackFrames []stackFrame
+
+func (fs stackFrames) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, f := range fs {
+		if err := enc.AppendObject(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseStack parses the frames of a stacktrace formatted by zap or by
+// errors with a %+v verb, where each function name is followed by a line
+// with a tab and "file:line". Other lines are ignored. It reports false if
+// s contains no frames.
+func parseStack(s string) (stackFrames, bool) {
+	var frames stackFrames
+	lines := strings.Split(s, "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		fn, loc := lines[i], lines[i+1]
+		if fn == "" || fn[0] == '\t' || !strings.HasPrefix(loc, "\t") {
+			continue
+		}
+		loc = loc[1:]
+		j := strings.LastIndexByte(loc, ':')
+		if j < 0 {
+			continue
+		}
+		line, err := strconv.Atoi(loc[j+1:])
+		if err != nil {
+			continue
+		}
+		frames = append(frames, stackFrame{Func: fn, File: loc[:j], Line: line})
+		i++
+	}
+	return frames, len(frames) > 0
+}
+
+// filterFrames returns the frames kept by the filter and the frame limit.
+func (o *encoderOptions) filterFrames(frames stackFrames) stackFrames {
+	if len(o.stackFilter) > 0 {
+		kept := frames[:0]
+		for _, f := range frames {
+			if !hasAnyPrefix(f.Func, o.stackFilter) {
+				kept = append(kept, f)
+			}
+		}
+		frames = kept
+	}
+	if n := o.stackMaxFrames; n > 0 && len(frames) > n {
+		frames = frames[:n]
+	}
+	return frames
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addStack writes the stacktrace stack under key in the stacktrace mode.
+func (enc *ltsvEncoder) addStack(key, stack string) {
+	o := enc.opts
+	if o.stackMode == StacktraceString && len(o.stackFilter) == 0 && o.stackMaxFrames <= 0 {
+		enc.AddString(key, stack)
+		return
+	}
+	frames, ok := parseStack(stack)
+	if !ok {
+		enc.AddString(key, stack)
+		return
+	}
+	frames = o.filterFrames(frames)
+	switch o.stackMode {
+	case StacktraceJoined:
+		var b strings.Builder
+		for i, f := range frames {
+			if i > 0 {
+				b.WriteString(o.stackSep)
+			}
+			b.WriteString(f.String())
+		}
+		enc.AddString(key, b.String())
+	case StacktraceLabels:
+		for i, f := range frames {
+			enc.AddString(key+"."+strconv.Itoa(i), f.String())
+		}
+	case StacktraceJSON:
+		// The frames are a single JSON label even if Flatten is set.
+		if enc.addKey(key) {
+			saved := enc.pushRedactPath(key)
+			enc.AppendArray(frames)
+			enc.redactPath = saved
+		}
+	default:
+		var b strings.Builder
+		for i, f := range frames {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(f.Func)
+			b.WriteString("\n\t")
+			b.WriteString(f.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(f.Line))
+		}
+		enc.AddString(key, b.String())
+	}
+}
@@ -0,0 +1,70 @@
+package ltsv_test
+
+import (
+	"testing"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestStacktrace(t *testing.T) {
+	cfg := ltsv.NewDevelopmentEncoderConfig()
+	cfg.TimeKey = ""
+
+	stack := "main.handle\n\t/app/main.go:12\nmain.main\n\t/app/main.go:30\nruntime.main\n\t/go/src/runtime/proc.go:250"
+	testCases := []struct {
+		opts  []ltsv.Option
+		stack string
+		want  string
+	}{
+		{
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace:main.handle\\n\\t/app/main.go:12\\nmain.main\\n\\t/app/main.go:30\\nruntime.main\\n\\t/go/src/runtime/proc.go:250\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.StacktraceFilter(ltsv.DefaultStacktraceFilter...)},
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace:main.handle\\n\\t/app/main.go:12\\nmain.main\\n\\t/app/main.go:30\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.Stacktrace(ltsv.StacktraceJoined)},
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace:main.handle /app/main.go:12 | main.main /app/main.go:30 | runtime.main /go/src/runtime/proc.go:250\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.Stacktrace(ltsv.StacktraceJoined), ltsv.StacktraceSeparator(" < "), ltsv.StacktraceMaxFrames(2)},
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace:main.handle /app/main.go:12 < main.main /app/main.go:30\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.Stacktrace(ltsv.StacktraceLabels), ltsv.StacktraceFilter("runtime.")},
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace.0:main.handle /app/main.go:12\tstacktrace.1:main.main /app/main.go:30\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.Stacktrace(ltsv.StacktraceJSON), ltsv.StacktraceMaxFrames(1)},
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace:[{\"func\":\"main.handle\",\"file\":\"/app/main.go\",\"line\":12}]\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.Stacktrace(ltsv.StacktraceJSON), ltsv.StacktraceMaxFrames(1), ltsv.Flatten(".", 0)},
+			stack: stack,
+			want:  "level:info\tmsg:hello\tstacktrace:[{\"func\":\"main.handle\",\"file\":\"/app/main.go\",\"line\":12}]\n",
+		},
+		{
+			opts:  []ltsv.Option{ltsv.Stacktrace(ltsv.StacktraceJSON)},
+			stack: "not a stacktrace",
+			want:  "level:info\tmsg:hello\tstacktrace:not a stacktrace\n",
+		},
+	}
+	for _, tc := range testCases {
+		enc := ltsv.NewLTSVEncoder(cfg, tc.opts...)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello", Stack: tc.stack}, nil)
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("got=%q, want=%q", got, tc.want)
+		}
+	}
+}
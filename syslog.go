@@ -0,0 +1,525 @@
+package ltsv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// A SyslogFormat selects the syslog message format written by a syslog
+// sink.
+type SyslogFormat int
+
+const (
+	// RFC5424Syslog writes messages in the format of RFC 5424. This is the
+	// default format.
+	RFC5424Syslog SyslogFormat = iota
+	// RFC3164Syslog writes messages in the BSD syslog format of RFC 3164.
+	RFC3164Syslog
+)
+
+// String returns a lower-case ASCII representation of the format.
+func (f SyslogFormat) String() string {
+	switch f {
+	case RFC5424Syslog:
+		return "rfc5424"
+	case RFC3164Syslog:
+		return "rfc3164"
+	default:
+		return fmt.Sprintf("SyslogFormat(%d)", int(f))
+	}
+}
+
+// MarshalText marshals the format to text. See String.
+func (f SyslogFormat) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText unmarshals text to a format. Valid values are "rfc5424"
+// and "rfc3164", or "5424" and "3164".
+func (f *SyslogFormat) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "rfc5424", "5424", "":
+		*f = RFC5424Syslog
+	case "rfc3164", "3164":
+		*f = RFC3164Syslog
+	default:
+		return fmt.Errorf("ltsv: unknown syslog format %q", text)
+	}
+	return nil
+}
+
+// DefaultSyslogFacility is the facility of a syslog sink unless another
+// one is set, user-level messages.
+const DefaultSyslogFacility = 1
+
+// syslogFacilities maps the names of syslog facilities to their codes.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// A SyslogConfig describes a syslog sink.
+//
+// The sink takes the LTSV lines written by the encoder as the messages,
+// and reads the level and the logger name from the labels LevelKey and
+// NameKey of each line to derive the priority and the app-name. Levels
+// written by zapcore.LowercaseLevelEncoder, zapcore.CapitalLevelEncoder,
+// ShortLevelEncoder and SyslogSeverityLevelEncoder are recognized. Lines
+// without a level are sent with the severity of InfoLevel.
+type SyslogConfig struct {
+	// Network is "unix", "unixgram", "udp" or "tcp". A "unix" address
+	// is tried as a datagram socket first, and as a stream socket if that
+	// fails.
+	Network string
+	Address string
+
+	Format SyslogFormat
+	// Facility is the syslog facility code, e.g. 16 for local0.
+	Facility int
+	// Hostname defaults to the name reported by os.Hostname.
+	Hostname string
+	// AppName is used for lines without a logger name. It defaults to the
+	// base name of the executable.
+	AppName string
+
+	// LevelKey and NameKey are the labels of the level and the logger
+	// name, usually the LevelKey and NameKey of the EncoderConfig.
+	LevelKey string
+	NameKey  string
+	// Escape is the escape mode of the encoder, used to read the labels.
+	Escape EscapeMode
+
+	// ReconnectDelay is the time to wait before reconnecting after the
+	// connection fails. It doubles after each failed attempt, up to
+	// MaxSyslogReconnectDelay. Zero means DefaultSyslogReconnectDelay.
+	ReconnectDelay time.Duration
+	// WriteTimeout limits the time to send a message. A message not sent
+	// in time fails like a broken connection, so that a stalled server
+	// does not block logging. Zero means DefaultSyslogWriteTimeout, and a
+	// negative timeout means no limit.
+	WriteTimeout time.Duration
+}
+
+// DefaultSyslogReconnectDelay is the ReconnectDelay of a syslog sink unless
+// another one is set.
+const DefaultSyslogReconnectDelay = 100 * time.Millisecond
+
+// MaxSyslogReconnectDelay is the longest delay between attempts to
+// reconnect to a syslog server.
+const MaxSyslogReconnectDelay = time.Minute
+
+// DefaultSyslogWriteTimeout is the WriteTimeout of a syslog sink unless
+// another one is set.
+const DefaultSyslogWriteTimeout = time.Second
+
+// NewSyslogConfig returns a SyslogConfig for the address on network with
+// the defaults of this package and the labels of the encoder configs of
+// this package.
+func NewSyslogConfig(network, address string) SyslogConfig {
+	return SyslogConfig{
+		Network:  network,
+		Address:  address,
+		Facility: DefaultSyslogFacility,
+		LevelKey: "level",
+		NameKey:  "logger",
+	}
+}
+
+// RegisterSyslogSink registers the syslog sink with zap for the URL
+// schemes "syslog+unix", "syslog+udp" and "syslog+tcp", so that syslog
+// addresses can be used in the OutputPaths of a zap.Config, e.g.
+//
+//	syslog+unix:///dev/log
+//	syslog+udp://127.0.0.1:514?facility=local0
+//	syslog+tcp://logs.example.com:601?format=rfc3164&app=web
+//
+// The query parameters are "facility", a name such as "local0" or a code,
+// "format", "hostname", "app", "levelKey", "nameKey", "escape",
+// "reconnectDelay" and "writeTimeout", durations such as "500ms", which
+// set the corresponding fields of the SyslogConfig returned by
+// NewSyslogConfig.
+func RegisterSyslogSink() error {
+	for _, network := range []string{"unix", "udp", "tcp"} {
+		if err := zap.RegisterSink("syslog+"+network, newSyslogSinkFromURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newSyslogSinkFromURL(u *url.URL) (zap.Sink, error) {
+	cfg, err := syslogConfigFromURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return NewSyslogSink(cfg)
+}
+
+// syslogConfigFromURL returns the configuration of a syslog URL.
+func syslogConfigFromURL(u *url.URL) (SyslogConfig, error) {
+	network := strings.TrimPrefix(u.Scheme, "syslog+")
+	address := u.Host
+	if network == "unix" {
+		address = u.Path
+	}
+	if address == "" {
+		return SyslogConfig{}, fmt.Errorf("ltsv: missing address in syslog URL %q", u)
+	}
+	cfg := NewSyslogConfig(network, address)
+	q := u.Query()
+	if s := q.Get("facility"); s != "" {
+		facility, ok := syslogFacilities[s]
+		if !ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 || n > 23 {
+				return SyslogConfig{}, fmt.Errorf("ltsv: unknown syslog facility %q", s)
+			}
+			facility = n
+		}
+		cfg.Facility = facility
+	}
+	if err := cfg.Format.UnmarshalText([]byte(q.Get("format"))); err != nil {
+		return SyslogConfig{}, err
+	}
+	if err := cfg.Escape.UnmarshalText([]byte(q.Get("escape"))); err != nil {
+		return SyslogConfig{}, err
+	}
+	cfg.Hostname = q.Get("hostname")
+	cfg.AppName = q.Get("app")
+	if q.Has("levelKey") {
+		cfg.LevelKey = q.Get("levelKey")
+	}
+	if q.Has("nameKey") {
+		cfg.NameKey = q.Get("nameKey")
+	}
+	if d := q.Get("reconnectDelay"); d != "" {
+		delay, err := time.ParseDuration(d)
+		if err != nil {
+			return SyslogConfig{}, fmt.Errorf("ltsv: invalid syslog reconnect delay %q", d)
+		}
+		cfg.ReconnectDelay = delay
+	}
+	if d := q.Get("writeTimeout"); d != "" {
+		timeout, err := time.ParseDuration(d)
+		if err != nil {
+			return SyslogConfig{}, fmt.Errorf("ltsv: invalid syslog write timeout %q", d)
+		}
+		cfg.WriteTimeout = timeout
+	}
+	return cfg, nil
+}
+
+// NewSyslogSink connects to the syslog server described by cfg and
+// returns a sink sending each line written to it as a syslog message.
+// Messages sent over stream sockets are framed with octet counting as
+// described in RFC 6587.
+//
+// If the connection fails, for example because the server is restarted,
+// the sink reconnects on a later write. Until then, writes fail and the
+// lines are lost.
+func NewSyslogSink(cfg SyslogConfig) (zap.Sink, error) {
+	if cfg.Facility < 0 || cfg.Facility > 23 {
+		return nil, fmt.Errorf("ltsv: invalid syslog facility %d", cfg.Facility)
+	}
+	if cfg.Format < RFC5424Syslog || cfg.Format > RFC3164Syslog {
+		return nil, fmt.Errorf("ltsv: unknown syslog format %d", int(cfg.Format))
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = filepath.Base(os.Args[0])
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = DefaultSyslogReconnectDelay
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = DefaultSyslogWriteTimeout
+	}
+	s := &syslogSink{
+		cfg:      cfg,
+		hostname: syslogField(cfg.Hostname, 255),
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type syslogSink struct {
+	cfg      SyslogConfig
+	hostname string
+	pid      string
+
+	mu     sync.Mutex
+	conn   net.Conn // nil while disconnected
+	stream bool
+	msg    []byte
+	closed bool
+
+	// retryAt is the time of the next attempt to reconnect, and delay is
+	// the delay before the one after it.
+	retryAt time.Time
+	delay   time.Duration
+}
+
+func (s *syslogSink) connect() error {
+	network := s.cfg.Network
+	var conn net.Conn
+	var err error
+	switch network {
+	case "unix":
+		conn, err = net.Dial("unixgram", s.cfg.Address)
+		if err != nil {
+			network = "unix"
+			conn, err = net.Dial(network, s.cfg.Address)
+		} else {
+			network = "unixgram"
+		}
+	case "unixgram", "udp", "tcp":
+		conn, err = net.Dial(network, s.cfg.Address)
+	default:
+		return fmt.Errorf("ltsv: unsupported syslog network %q", network)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.stream = network == "unix" || network == "tcp"
+	return nil
+}
+
+// Write sends each line of p as a message.
+func (s *syslogSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, errors.New("ltsv: syslog sink is closed")
+	}
+	if err := s.reconnect(); err != nil {
+		return 0, err
+	}
+	for rest := p; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i], rest[i+1:]
+		} else {
+			rest = nil
+		}
+		if len(line) == 0 {
+			continue
+		}
+		if err := s.send(line); err != nil {
+			return len(p) - len(rest) - len(line), err
+		}
+	}
+	return len(p), nil
+}
+
+// reconnect connects to the server if the sink is disconnected and the
+// reconnect delay has passed.
+func (s *syslogSink) reconnect() error {
+	if s.conn != nil {
+		return nil
+	}
+	if time.Now().Before(s.retryAt) {
+		return errors.New("ltsv: syslog server is unavailable")
+	}
+	if err := s.connect(); err != nil {
+		s.disconnect()
+		return err
+	}
+	s.delay = 0
+	return nil
+}
+
+// disconnect closes the connection and schedules the next attempt to
+// reconnect.
+func (s *syslogSink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	if s.delay == 0 {
+		s.delay = s.cfg.ReconnectDelay
+	}
+	s.retryAt = time.Now().Add(s.delay)
+	if s.delay *= 2; s.delay > MaxSyslogReconnectDelay {
+		s.delay = MaxSyslogReconnectDelay
+	}
+}
+
+// send sends line as a message, reconnecting at once if the connection is
+// broken. If the server does not take the message in time, the sink
+// disconnects instead, and reconnects after the delay.
+func (s *syslogSink) send(line []byte) error {
+	s.msg = s.appendMessage(s.msg[:0], line)
+	err := s.write()
+	if err == nil {
+		return nil
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		s.disconnect()
+		return err
+	}
+	s.conn.Close()
+	if err := s.connect(); err != nil {
+		s.conn = nil
+		s.disconnect()
+		return err
+	}
+	if err := s.write(); err != nil {
+		s.disconnect()
+		return err
+	}
+	return nil
+}
+
+// write writes the message to the connection within the write timeout.
+func (s *syslogSink) write() error {
+	if s.cfg.WriteTimeout > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := s.conn.Write(s.msg)
+	return err
+}
+
+// appendMessage appends the framed syslog message of line to b.
+func (s *syslogSink) appendMessage(b, line []byte) []byte {
+	level, appName := zapcore.InfoLevel, s.cfg.AppName
+	if rec, err := ParseRecord(line, s.cfg.Escape); err == nil {
+		if v, ok := rec.Get(s.cfg.LevelKey); ok && s.cfg.LevelKey != "" {
+			if l, ok := parseSyslogLevel(v); ok {
+				level = l
+			}
+		}
+		if v, ok := rec.Get(s.cfg.NameKey); ok && s.cfg.NameKey != "" && v != "" {
+			appName = v
+		}
+	}
+
+	start := len(b)
+	if s.stream {
+		// Reserve room for the length of octet counting.
+		b = append(b, "0000000000 "...)
+	}
+	header := len(b)
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(s.cfg.Facility*8+SyslogSeverity(level)), 10)
+	b = append(b, '>')
+	now := time.Now()
+	if s.cfg.Format == RFC3164Syslog {
+		b = now.AppendFormat(b, time.Stamp)
+		b = append(b, ' ')
+		b = append(b, s.hostname...)
+		b = append(b, ' ')
+		// The TAG, with the pid, is limited to 32 characters.
+		max := 32 - len(s.pid) - 2
+		if max < 1 {
+			max = 1
+		}
+		b = append(b, syslogField(appName, max)...)
+		b = append(b, '[')
+		b = append(b, s.pid...)
+		b = append(b, "]: "...)
+	} else {
+		b = append(b, "1 "...)
+		b = now.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+		b = append(b, ' ')
+		b = append(b, s.hostname...)
+		b = append(b, ' ')
+		b = append(b, syslogField(appName, 48)...)
+		b = append(b, ' ')
+		b = append(b, s.pid...)
+		b = append(b, " - - "...)
+	}
+	b = append(b, line...)
+	if !s.stream {
+		return b
+	}
+	n := strconv.AppendInt(nil, int64(len(b)-header), 10)
+	prefix := header - len(n) - 1
+	copy(b[prefix:], n)
+	b[header-1] = ' '
+	return append(b[:start], b[prefix:]...)
+}
+
+// parseSyslogLevel parses a level written by one of the level encoders
+// known to the syslog sink.
+func parseSyslogLevel(s string) (zapcore.Level, bool) {
+	for _, decode := range []LevelDecoder{TextLevelDecoder, ShortLevelDecoder, SyslogSeverityLevelDecoder} {
+		if l, err := decode(s); err == nil {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// syslogField returns s as a header field of at most max characters,
+// replacing spaces and characters other than printable ASCII with
+// underscores, or "-" if s is empty.
+func syslogField(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func (s *syslogSink) Sync() error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
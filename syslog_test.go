@@ -0,0 +1,337 @@
+package ltsv_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var registerSyslogSink sync.Once
+
+func TestSyslogSink(t *testing.T) {
+	registerSyslogSink.Do(func() {
+		if err := ltsv.RegisterSyslogSink(); err != nil {
+			t.Fatalf("failed to register syslog sink; err=%+v", err)
+		}
+	})
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen; err=%+v", err)
+	}
+	defer udp.Close()
+	sockPath := filepath.Join(t.TempDir(), "log.sock")
+	unixgram, err := net.ListenPacket("unixgram", sockPath)
+	if err != nil {
+		t.Fatalf("failed to listen; err=%+v", err)
+	}
+	defer unixgram.Close()
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen; err=%+v", err)
+	}
+	defer tcp.Close()
+	tcpMessages := make(chan string, 2)
+	go readOctetCounted(tcp, nil, tcpMessages)
+	readPacket := func(conn net.PacketConn) func() string {
+		return func() string {
+			buf := make([]byte, 4096)
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("failed to read; err=%+v", err)
+			}
+			return string(buf[:n])
+		}
+	}
+	hostname, _ := os.Hostname()
+	pid := strconv.Itoa(os.Getpid())
+
+	testCases := []struct {
+		url    string
+		read   func() string
+		level  zapcore.Level
+		name   string
+		header []string
+	}{
+		{
+			url:    "syslog+udp://" + udp.LocalAddr().String() + "?facility=local0",
+			read:   readPacket(udp),
+			level:  zapcore.ErrorLevel,
+			name:   "web",
+			header: []string{"<131>1", "", hostname, "web", pid, "-", "-"},
+		},
+		{
+			url:    "syslog+unix://" + sockPath + "?hostname=host1&app=fallback",
+			read:   readPacket(unixgram),
+			level:  zapcore.InfoLevel,
+			header: []string{"<14>1", "", "host1", "fallback", pid, "-", "-"},
+		},
+		{
+			url:    "syslog+tcp://" + tcp.Addr().String() + "?facility=3&hostname=host1",
+			read:   func() string { return <-tcpMessages },
+			level:  zapcore.WarnLevel,
+			name:   "a b",
+			header: []string{"<28>1", "", "host1", "a_b", pid, "-", "-"},
+		},
+	}
+	for _, tc := range testCases {
+		sink, closeSink, err := zap.Open(tc.url)
+		if err != nil {
+			t.Fatalf("failed to open %s; err=%+v", tc.url, err)
+		}
+		cfg := ltsv.NewProductionEncoderConfig()
+		cfg.TimeKey = ""
+		logger := zap.New(zapcore.NewCore(ltsv.NewLTSVEncoder(cfg), sink, zapcore.DebugLevel))
+		if tc.name != "" {
+			logger = logger.Named(tc.name)
+		}
+		if ce := logger.Check(tc.level, "hello"); ce != nil {
+			ce.Write(zap.String("user", "jane"))
+		}
+		got := tc.read()
+		closeSink()
+
+		parts := strings.SplitN(got, " ", len(tc.header)+1)
+		if len(parts) != len(tc.header)+1 {
+			t.Fatalf("malformed message %q", got)
+		}
+		parts[1] = "" // timestamp
+		for i, want := range tc.header {
+			if parts[i] != want {
+				t.Errorf("header field %d: got=%q, want=%q in %q", i, parts[i], want, got)
+			}
+		}
+		level := tc.level.String()
+		wantMsg := "level:" + level + "\tmsg:hello\tuser:jane"
+		if tc.name != "" {
+			wantMsg = "level:" + level + "\tlogger:" + tc.name + "\tmsg:hello\tuser:jane"
+		}
+		if msg := parts[len(parts)-1]; msg != wantMsg {
+			t.Errorf("message: got=%q, want=%q", msg, wantMsg)
+		}
+	}
+}
+
+func TestSyslogSinkRFC3164(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen; err=%+v", err)
+	}
+	defer conn.Close()
+	cfg := ltsv.NewSyslogConfig("udp", conn.LocalAddr().String())
+	cfg.Format = ltsv.RFC3164Syslog
+	cfg.Hostname = "host1"
+	cfg.LevelKey = "severity"
+	sink, err := ltsv.NewSyslogSink(cfg)
+	if err != nil {
+		t.Fatalf("failed to create sink; err=%+v", err)
+	}
+	defer sink.Close()
+	pid := strconv.Itoa(os.Getpid())
+	longName := strings.Repeat("a", 40)
+	for _, tc := range []struct {
+		name string
+		tag  string
+	}{
+		{name: "app", tag: "app[" + pid + "]"},
+		{name: longName, tag: longName[:32-len(pid)-2] + "[" + pid + "]"},
+	} {
+		line := "severity:3\tlogger:" + tc.name + "\tmsg:hello"
+		if _, err := sink.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("failed to write; err=%+v", err)
+		}
+		buf := make([]byte, 4096)
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to read; err=%+v", err)
+		}
+		got := string(buf[:n])
+		// The timestamp is like "Jan  2 15:04:05".
+		if !strings.HasPrefix(got, "<11>") || len(got) < 4+15 {
+			t.Fatalf("malformed message %q", got)
+		}
+		want := " host1 " + tc.tag + ": " + line
+		if rest := got[4+15:]; rest != want {
+			t.Errorf("got=%q, want=%q", rest, want)
+		}
+	}
+}
+
+func TestSyslogSinkURLError(t *testing.T) {
+	registerSyslogSink.Do(func() {
+		if err := ltsv.RegisterSyslogSink(); err != nil {
+			t.Fatalf("failed to register syslog sink; err=%+v", err)
+		}
+	})
+	for _, u := range []string{
+		"syslog+udp://127.0.0.1:514?facility=nope",
+		"syslog+udp://127.0.0.1:514?format=rfc1",
+		"syslog+unix://",
+	} {
+		if _, _, err := zap.Open(u); err == nil {
+			t.Errorf("no error for %s", u)
+		}
+	}
+}
+
+// readOctetCounted accepts a connection on l, passes it to conns if not
+// nil, and sends the octet-counted messages read from it to messages.
+func readOctetCounted(l net.Listener, conns chan<- net.Conn, messages chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	if conns != nil {
+		conns <- conn
+	}
+	r := bufio.NewReader(conn)
+	for {
+		n, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(n, " "))
+		if err != nil {
+			messages <- "invalid length " + n
+			return
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		messages <- string(msg)
+	}
+}
+
+func TestSyslogSinkReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen; err=%+v", err)
+	}
+	addr := l.Addr().String()
+	messages := make(chan string, 100)
+	conns := make(chan net.Conn, 1)
+	go readOctetCounted(l, conns, messages)
+
+	cfg := ltsv.NewSyslogConfig("tcp", addr)
+	cfg.ReconnectDelay = 10 * time.Millisecond
+	sink, err := ltsv.NewSyslogSink(cfg)
+	if err != nil {
+		t.Fatalf("failed to create sink; err=%+v", err)
+	}
+	defer sink.Close()
+	waitFor := func(want string) bool {
+		timeout := time.After(100 * time.Millisecond)
+		for {
+			select {
+			case msg := <-messages:
+				if strings.HasSuffix(msg, want) {
+					return true
+				}
+			case <-timeout:
+				return false
+			}
+		}
+	}
+	if _, err := sink.Write([]byte("msg:one\n")); err != nil {
+		t.Fatalf("failed to write; err=%+v", err)
+	}
+	if !waitFor("msg:one") {
+		t.Fatal("first message not received")
+	}
+
+	// Stop the server, and write until the sink notices.
+	l.Close()
+	(<-conns).Close()
+	var writeErr error
+	for i := 0; i < 100 && writeErr == nil; i++ {
+		_, writeErr = sink.Write([]byte("msg:lost\n"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if writeErr == nil {
+		t.Fatal("no error after the server stopped")
+	}
+
+	// Restart the server on the same address.
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to listen again; err=%+v", err)
+	}
+	defer l.Close()
+	go readOctetCounted(l, nil, messages)
+	received := false
+	for i := 0; i < 50 && !received; i++ {
+		if _, err := sink.Write([]byte("msg:two\n")); err != nil {
+			if strings.Contains(err.Error(), "closed") {
+				t.Fatalf("sink closed after reconnect failure; err=%+v", err)
+			}
+			time.Sleep(20 * time.Millisecond)
+			continue
+		}
+		received = waitFor("msg:two")
+	}
+	if !received {
+		t.Fatal("no message received after the server restarted")
+	}
+
+	sink.Close()
+	if _, err := sink.Write([]byte("msg:three\n")); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("got err=%v after Close, want closed error", err)
+	}
+}
+
+func TestSyslogSinkWriteTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen; err=%+v", err)
+	}
+	defer l.Close()
+	// The server accepts the connection but never reads from it.
+	conns := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			conns <- conn
+		}
+	}()
+
+	cfg := ltsv.NewSyslogConfig("tcp", l.Addr().String())
+	cfg.WriteTimeout = 10 * time.Millisecond
+	cfg.ReconnectDelay = time.Minute
+	sink, err := ltsv.NewSyslogSink(cfg)
+	if err != nil {
+		t.Fatalf("failed to create sink; err=%+v", err)
+	}
+	defer sink.Close()
+	conn := <-conns
+	defer conn.Close()
+
+	line := []byte("msg:" + strings.Repeat("x", 64*1024) + "\n")
+	var writeErr error
+	for i := 0; i < 10000 && writeErr == nil; i++ {
+		_, writeErr = sink.Write(line)
+	}
+	var ne net.Error
+	if !errors.As(writeErr, &ne) || !ne.Timeout() {
+		t.Fatalf("got err=%v, want timeout", writeErr)
+	}
+	start := time.Now()
+	if _, err := sink.Write(line); err == nil {
+		t.Error("no error while disconnected")
+	}
+	if d := time.Since(start); d > cfg.WriteTimeout {
+		t.Errorf("write took %v while disconnected", d)
+	}
+}
@@ -0,0 +1,120 @@
+package ltsv
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// CLFTimeEncoder serializes a time.Time in the Common Log Format used by
+// Apache and nginx access logs, e.g. "[10/Oct/2000:13:55:36 -0700]".
+func CLFTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.Format(clfLayout))
+}
+
+const clfLayout = "[02/Jan/2006:15:04:05 -0700]"
+
+// NginxISO8601TimeEncoder serializes a time.Time like nginx's
+// $time_iso8601, e.g. "2000-10-10T13:55:36-07:00". Unlike RFC 3339 times
+// written by zapcore, UTC is written as "+00:00" rather than "Z".
+func NginxISO8601TimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.Format("2006-01-02T15:04:05-07:00"))
+}
+
+// RFC3339TimeEncoder returns a TimeEncoder which serializes a time.Time
+// in RFC 3339 format with exactly digits fractional digits, e.g.
+// "2000-10-10T13:55:36.123-07:00" for 3 digits. The number of digits is
+// clamped between 0 and 9.
+func RFC3339TimeEncoder(digits int) zapcore.TimeEncoder {
+	if digits < 0 {
+		digits = 0
+	} else if digits > 9 {
+		digits = 9
+	}
+	layout := "2006-01-02T15:04:05Z07:00"
+	if digits > 0 {
+		layout = "2006-01-02T15:04:05." + strings.Repeat("0", digits) + "Z07:00"
+	}
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.Format(layout))
+	}
+}
+
+// UnixMilliTimeEncoder serializes a time.Time as an integer number of
+// milliseconds since the Unix epoch.
+func UnixMilliTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.UnixNano() / int64(time.Millisecond))
+}
+
+// UnixMicroTimeEncoder serializes a time.Time as an integer number of
+// microseconds since the Unix epoch.
+func UnixMicroTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(t.UnixNano() / int64(time.Microsecond))
+}
+
+// MsecTimeEncoder serializes a time.Time like nginx's $msec, as seconds
+// since the Unix epoch with exactly three fractional digits, e.g.
+// "971211336.123". It is written as a string, so it is quoted inside JSON
+// values.
+func MsecTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(formatFixed(t.UnixNano()/int64(time.Millisecond), 3))
+}
+
+// TimeEncoderIn returns a TimeEncoder which converts times to loc before
+// serializing them with timeEncoder.
+func TimeEncoderIn(loc *time.Location, timeEncoder zapcore.TimeEncoder) zapcore.TimeEncoder {
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		timeEncoder(t.In(loc), enc)
+	}
+}
+
+// ReqtimeDurationEncoder serializes a time.Duration like nginx's
+// $request_time, as seconds with exactly three fractional digits, e.g.
+// "0.123". It is written as a string, so it is quoted inside JSON values.
+func ReqtimeDurationEncoder(d time.Duration, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(formatFixed(int64(d/time.Millisecond), 3))
+}
+
+// MicrosDurationEncoder serializes a time.Duration as an integer number of
+// microseconds, like Apache's %D and the reqtime_microsec label.
+func MicrosDurationEncoder(d time.Duration, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(int64(d / time.Microsecond))
+}
+
+// formatFixed formats n / 10^scale with exactly scale fractional digits.
+func formatFixed(n int64, scale int) string {
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+	s := strconv.FormatInt(n, 10)
+	if len(s) <= scale {
+		s = strings.Repeat("0", scale-len(s)+1) + s
+	}
+	s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// NewApacheEncoderConfig returns an EncoderConfig which writes times in
+// the Common Log Format and durations in microseconds, as Apache does
+// with %t and %D.
+func NewApacheEncoderConfig() zapcore.EncoderConfig {
+	cfg := NewProductionEncoderConfig()
+	cfg.EncodeTime = CLFTimeEncoder
+	cfg.EncodeDuration = MicrosDurationEncoder
+	return cfg
+}
+
+// NewNginxEncoderConfig returns an EncoderConfig which writes times and
+// durations as nginx does with $time_iso8601 and $request_time.
+func NewNginxEncoderConfig() zapcore.EncoderConfig {
+	cfg := NewProductionEncoderConfig()
+	cfg.EncodeTime = NginxISO8601TimeEncoder
+	cfg.EncodeDuration = ReqtimeDurationEncoder
+	return cfg
+}
@@ -0,0 +1,62 @@
+package ltsv_test
+
+import (
+	"testing"
+	"time"
+
+	ltsv "github.com/hnakamur/zap-ltsv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTimeEncoders(t *testing.T) {
+	loc := time.FixedZone("", -7*60*60)
+	tm := time.Date(2000, 10, 10, 13, 55, 36, 123456789, loc)
+	testCases := []struct {
+		encodeTime zapcore.TimeEncoder
+		want       string
+	}{
+		{encodeTime: ltsv.CLFTimeEncoder, want: "[10/Oct/2000:13:55:36 -0700]"},
+		{encodeTime: ltsv.NginxISO8601TimeEncoder, want: "2000-10-10T13:55:36-07:00"},
+		{encodeTime: ltsv.RFC3339TimeEncoder(0), want: "2000-10-10T13:55:36-07:00"},
+		{encodeTime: ltsv.RFC3339TimeEncoder(3), want: "2000-10-10T13:55:36.123-07:00"},
+		{encodeTime: ltsv.RFC3339TimeEncoder(20), want: "2000-10-10T13:55:36.123456789-07:00"},
+		{encodeTime: ltsv.UnixMilliTimeEncoder, want: "971211336123"},
+		{encodeTime: ltsv.UnixMicroTimeEncoder, want: "971211336123456"},
+		{encodeTime: ltsv.MsecTimeEncoder, want: "971211336.123"},
+		{encodeTime: ltsv.TimeEncoderIn(time.UTC, ltsv.NginxISO8601TimeEncoder), want: "2000-10-10T20:55:36+00:00"},
+		{encodeTime: ltsv.TimeEncoderIn(time.UTC, ltsv.RFC3339TimeEncoder(0)), want: "2000-10-10T20:55:36Z"},
+	}
+	for _, tc := range testCases {
+		cfg := zapcore.EncoderConfig{EncodeTime: tc.encodeTime}
+		buf, err := ltsv.NewLTSVEncoder(cfg).EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Time("time", tm)})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got, want := buf.String(), "time:"+tc.want+"\n"; got != want {
+			t.Errorf("got=%q, want=%q", got, want)
+		}
+	}
+}
+
+func TestDurationEncoders(t *testing.T) {
+	testCases := []struct {
+		encodeDuration zapcore.DurationEncoder
+		d              time.Duration
+		want           string
+	}{
+		{encodeDuration: ltsv.ReqtimeDurationEncoder, d: 1234567 * time.Microsecond, want: "1.234"},
+		{encodeDuration: ltsv.ReqtimeDurationEncoder, d: 5 * time.Millisecond, want: "0.005"},
+		{encodeDuration: ltsv.MicrosDurationEncoder, d: 1234567 * time.Nanosecond, want: "1234"},
+	}
+	for _, tc := range testCases {
+		cfg := zapcore.EncoderConfig{EncodeDuration: tc.encodeDuration}
+		buf, err := ltsv.NewLTSVEncoder(cfg).EncodeEntry(zapcore.Entry{}, []zapcore.Field{zap.Duration("reqtime", tc.d)})
+		if err != nil {
+			t.Fatalf("failed to encode entry; err=%+v", err)
+		}
+		if got, want := buf.String(), "reqtime:"+tc.want+"\n"; got != want {
+			t.Errorf("got=%q, want=%q", got, want)
+		}
+	}
+}